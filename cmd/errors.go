@@ -14,4 +14,9 @@ var (
 	errRequiredTopics    = errors.New("at least one topic is required")
 	errInvalidKubeClient = errors.New("failed to create kubernetes client")
 	errInvalidHelmChart  = errors.New("failed to load helm chart")
+	errAuditDestination  = errors.New("audit records can be written to a file or a subject, not both")
+	errAuditDevMode      = errors.New("audit records cannot be published to a subject in dev mode, use --audit-log-path instead")
+	errOutboxBucket      = errors.New("a status outbox kv bucket is required outside of dev mode")
+	errReplayFile        = errors.New("a file of messages to replay is required")
+	errReplayFailed      = errors.New("replayed message failed")
 )
@@ -3,23 +3,25 @@ package cmd
 import (
 	"context"
 	"errors"
+	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
-	"github.com/lestrrat-go/backoff/v2"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"go.infratographer.com/ipam-api/pkg/ipamclient"
 	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
 	metadata "go.infratographer.com/metadata-api/pkg/client"
+	"go.infratographer.com/x/echojwtx"
 	"go.infratographer.com/x/echox"
 	"go.infratographer.com/x/events"
 	"go.infratographer.com/x/oauth2x"
@@ -28,6 +30,7 @@ import (
 	"go.infratographer.com/x/viperx"
 
 	"go.infratographer.com/load-balancer-operator/internal/config"
+	"go.infratographer.com/load-balancer-operator/internal/memevents"
 	"go.infratographer.com/load-balancer-operator/internal/srv"
 )
 
@@ -42,14 +45,25 @@ var processCmd = &cobra.Command{
 }
 
 var (
-	processDevMode     bool
-	defaultCooldown    = 5
-	defaultJitter      = 0.5
-	defaultMaxInterval = 2 * time.Minute
+	processDevMode bool
+
+	// defaultRetryPolicies are the retry policies used for operations that are not configured
+	defaultRetryPolicies = map[string]config.RetryPolicyConfig{
+		"api-lookup":       {MaxRetries: 3, MinInterval: time.Second, MaxInterval: 30 * time.Second, Jitter: 0.5},
+		"install":          {MaxRetries: 3, MinInterval: time.Second, MaxInterval: 2 * time.Minute, Jitter: 0.5},
+		"upgrade":          {MaxRetries: 5, MinInterval: time.Second, MaxInterval: 2 * time.Minute, Jitter: 0.5},
+		"uninstall":        {MaxRetries: 3, MinInterval: time.Second, MaxInterval: 2 * time.Minute, Jitter: 0.5},
+		"namespace-delete": {MaxRetries: 3, MinInterval: time.Second, MaxInterval: time.Minute, Jitter: 0.5},
+		"metadata-update":  {MaxRetries: 5, MinInterval: time.Second, MaxInterval: time.Minute, Jitter: 0.5},
+	}
 )
 
 const (
 	DefaultLBMetricsPort = 29782
+
+	// devAckWait is how long the in-memory connection of dev mode waits for a message to be
+	// acked before redelivering it, matching the JetStream default
+	devAckWait = 30 * time.Second
 )
 
 func init() {
@@ -92,6 +106,96 @@ func init() {
 	processCmd.PersistentFlags().Int("loadbalancer-metrics-port", DefaultLBMetricsPort, "port to expose deployed load balancer metrics on")
 	viperx.MustBindFlag(viper.GetViper(), "loadbalancer-metrics-port", processCmd.PersistentFlags().Lookup("loadbalancer-metrics-port"))
 
+	processCmd.PersistentFlags().Int("dedupe-cache-size", srv.DefaultDedupeCacheSize, "number of processed message keys to remember for duplicate detection")
+	viperx.MustBindFlag(viper.GetViper(), "dedupe-cache-size", processCmd.PersistentFlags().Lookup("dedupe-cache-size"))
+
+	processCmd.PersistentFlags().String("dedupe-kv-bucket", "", "optional NATS KV bucket used to share processed message keys across restarts and replicas")
+	viperx.MustBindFlag(viper.GetViper(), "dedupe-kv-bucket", processCmd.PersistentFlags().Lookup("dedupe-kv-bucket"))
+
+	processCmd.PersistentFlags().Duration("dedupe-ttl", time.Hour, "how long processed message keys are kept in the dedupe KV bucket")
+	viperx.MustBindFlag(viper.GetViper(), "dedupe-ttl", processCmd.PersistentFlags().Lookup("dedupe-ttl"))
+
+	processCmd.PersistentFlags().String("status-outbox-kv-bucket", srv.DefaultOutboxBucket, "NATS KV bucket used to keep undelivered status updates across restarts, created when it does not exist; not used in dev mode")
+	viperx.MustBindFlag(viper.GetViper(), "status-outbox.kv-bucket", processCmd.PersistentFlags().Lookup("status-outbox-kv-bucket"))
+
+	processCmd.PersistentFlags().Duration("status-outbox-interval", srv.DefaultOutboxInterval, "how often undelivered status updates are checked for redelivery")
+	viperx.MustBindFlag(viper.GetViper(), "status-outbox.interval", processCmd.PersistentFlags().Lookup("status-outbox-interval"))
+
+	processCmd.PersistentFlags().Duration("status-outbox-min-backoff", srv.DefaultOutboxMinBackoff, "delay before the first redelivery of a status update")
+	viperx.MustBindFlag(viper.GetViper(), "status-outbox.min-backoff", processCmd.PersistentFlags().Lookup("status-outbox-min-backoff"))
+
+	processCmd.PersistentFlags().Duration("status-outbox-max-backoff", srv.DefaultOutboxMaxBackoff, "longest delay between redeliveries of a status update")
+	viperx.MustBindFlag(viper.GetViper(), "status-outbox.max-backoff", processCmd.PersistentFlags().Lookup("status-outbox-max-backoff"))
+
+	processCmd.PersistentFlags().Int("status-outbox-max-pending", srv.DefaultOutboxMaxPending, "undelivered status updates kept for each load balancer, older updates are dropped once it is exceeded")
+	viperx.MustBindFlag(viper.GetViper(), "status-outbox.max-pending", processCmd.PersistentFlags().Lookup("status-outbox-max-pending"))
+
+	processCmd.PersistentFlags().String("audit-log-path", "", "optional file that a JSON line is appended to for every change made to namespaces, helm releases and load balancer metadata")
+	viperx.MustBindFlag(viper.GetViper(), "audit.path", processCmd.PersistentFlags().Lookup("audit-log-path"))
+
+	processCmd.PersistentFlags().String("audit-log-subject", "", "optional NATS subject that audit records are published to instead of a file; the subject must be captured by a JetStream stream and is not supported in dev mode")
+	viperx.MustBindFlag(viper.GetViper(), "audit.subject", processCmd.PersistentFlags().Lookup("audit-log-subject"))
+
+	processCmd.PersistentFlags().String("oidc-audience", "", "expected audience of tokens presented to the admin API; the admin API is authenticated when an OIDC client issuer is configured")
+	viperx.MustBindFlag(viper.GetViper(), "oidc.audience", processCmd.PersistentFlags().Lookup("oidc-audience"))
+
+	processCmd.PersistentFlags().Bool("admin-insecure", false, "serve the admin API without authentication when no OIDC issuer is configured")
+	viperx.MustBindFlag(viper.GetViper(), "admin-insecure", processCmd.PersistentFlags().Lookup("admin-insecure"))
+
+	processCmd.PersistentFlags().Duration("inventory-interval", srv.DefaultInventoryInterval, "how often managed load balancers and their helm releases are resynced for metrics; zero disables the resync")
+	viperx.MustBindFlag(viper.GetViper(), "inventory-interval", processCmd.PersistentFlags().Lookup("inventory-interval"))
+
+	processCmd.PersistentFlags().Duration("timeouts-api-lookup", 30*time.Second, "timeout for looking up a load balancer from the API")
+	viperx.MustBindFlag(viper.GetViper(), "timeouts.api-lookup", processCmd.PersistentFlags().Lookup("timeouts-api-lookup"))
+
+	processCmd.PersistentFlags().Duration("timeouts-namespace", 30*time.Second, "timeout for applying or removing a load balancer namespace")
+	viperx.MustBindFlag(viper.GetViper(), "timeouts.namespace", processCmd.PersistentFlags().Lookup("timeouts-namespace"))
+
+	processCmd.PersistentFlags().Duration("timeouts-helm", 5*time.Minute, "timeout for a helm install or upgrade of a load balancer")
+	viperx.MustBindFlag(viper.GetViper(), "timeouts.helm", processCmd.PersistentFlags().Lookup("timeouts-helm"))
+
+	processCmd.PersistentFlags().Duration("timeouts-namespace-termination", 5*time.Minute, "how long to wait for a deleted load balancer namespace to terminate before reporting it as stuck")
+	viperx.MustBindFlag(viper.GetViper(), "timeouts.namespace-termination", processCmd.PersistentFlags().Lookup("timeouts-namespace-termination"))
+
+	processCmd.PersistentFlags().Duration("timeouts-metadata", 30*time.Second, "timeout for updating load balancer status metadata")
+	viperx.MustBindFlag(viper.GetViper(), "timeouts.metadata", processCmd.PersistentFlags().Lookup("timeouts-metadata"))
+
+	processCmd.PersistentFlags().StringToString("namespace-labels", nil, "static labels applied to every load balancer namespace")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.labels", processCmd.PersistentFlags().Lookup("namespace-labels"))
+
+	processCmd.PersistentFlags().StringToString("namespace-annotations", nil, "static annotations applied to every load balancer namespace")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.annotations", processCmd.PersistentFlags().Lookup("namespace-annotations"))
+
+	processCmd.PersistentFlags().String("namespace-pod-security-enforce", "", "pod security admission level enforced in load balancer namespaces, not enforced when empty")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.pod-security.enforce", processCmd.PersistentFlags().Lookup("namespace-pod-security-enforce"))
+
+	processCmd.PersistentFlags().String("namespace-pod-security-audit", "restricted", "pod security admission level audited in load balancer namespaces")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.pod-security.audit", processCmd.PersistentFlags().Lookup("namespace-pod-security-audit"))
+
+	processCmd.PersistentFlags().String("namespace-pod-security-warn", "restricted", "pod security admission level warned on in load balancer namespaces")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.pod-security.warn", processCmd.PersistentFlags().Lookup("namespace-pod-security-warn"))
+
+	processCmd.PersistentFlags().String("namespace-pod-security-version", "latest", "pod security admission policy version applied to load balancer namespaces")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.pod-security.version", processCmd.PersistentFlags().Lookup("namespace-pod-security-version"))
+
+	processCmd.PersistentFlags().StringToString("namespace-resource-quota", nil, "hard resource quota applied to every load balancer namespace, e.g. pods=10,limits.cpu=4")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.resource-quota", processCmd.PersistentFlags().Lookup("namespace-resource-quota"))
+
+	processCmd.PersistentFlags().StringToString("namespace-limit-range-default", nil, "default container limits in load balancer namespaces, e.g. cpu=500m,memory=256Mi")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.limit-range.default", processCmd.PersistentFlags().Lookup("namespace-limit-range-default"))
+
+	processCmd.PersistentFlags().StringToString("namespace-limit-range-default-request", nil, "default container requests in load balancer namespaces")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.limit-range.default-request", processCmd.PersistentFlags().Lookup("namespace-limit-range-default-request"))
+
+	processCmd.PersistentFlags().StringToString("namespace-limit-range-max", nil, "maximum container limits in load balancer namespaces")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.limit-range.max", processCmd.PersistentFlags().Lookup("namespace-limit-range-max"))
+
+	processCmd.PersistentFlags().Bool("namespace-network-policy", false, "apply a default-deny ingress network policy allowing only TCP traffic to load balancer and metrics ports")
+	viperx.MustBindFlag(viper.GetViper(), "namespace.network-policy", processCmd.PersistentFlags().Lookup("namespace-network-policy"))
+
+	processCmd.PersistentFlags().Bool("deployment-status", true, "maintain a LoadBalancerDeployment resource in each load balancer namespace; requires the chart CRDs")
+	viperx.MustBindFlag(viper.GetViper(), "deployment-status", processCmd.PersistentFlags().Lookup("deployment-status"))
+
 	processCmd.Flags().String("metadata-status-namespace-id", "", "loadbalancer metadata status namespace id")
 	viperx.MustBindFlag(viper.GetViper(), "metadata.status-namespace-id", processCmd.Flags().Lookup("metadata-status-namespace-id"))
 
@@ -101,6 +205,14 @@ func init() {
 	processCmd.Flags().String("metadata-source", "load-balancer-operator", "metadata-api endpoint")
 	viperx.MustBindFlag(viper.GetViper(), "metadata.source", processCmd.Flags().Lookup("metadata-source"))
 
+	// retry policies are only configurable through the config file or environment
+	for op, policy := range defaultRetryPolicies {
+		viper.SetDefault("retry."+op+".max-retries", policy.MaxRetries)
+		viper.SetDefault("retry."+op+".min-interval", policy.MinInterval)
+		viper.SetDefault("retry."+op+".max-interval", policy.MaxInterval)
+		viper.SetDefault("retry."+op+".jitter", policy.Jitter)
+	}
+
 	rootCmd.AddCommand(processCmd)
 }
 
@@ -117,6 +229,9 @@ func process(ctx context.Context, logger *zap.SugaredLogger) error {
 		return err
 	}
 
+	// trace kubernetes API requests, including those made by helm
+	client = srv.InstrumentConfig(client)
+
 	chart, err := loadHelmChart(viper.GetString("chart-path"))
 	if err != nil {
 		logger.Fatalw("failed to load helm chart from provided path", "error", err)
@@ -135,32 +250,98 @@ func process(ctx context.Context, logger *zap.SugaredLogger) error {
 		logger.Fatal("failed to initialize new server", zap.Error(err))
 	}
 
-	conn, err := events.NewConnection(config.AppConfig.Events, events.WithLogger(logger))
+	conn, err := newEventsConnection(logger)
 	if err != nil {
 		logger.Fatalw("failed to create new events connection", "error", err)
 	}
 
-	backoffPolicy := backoff.Exponential(
-		backoff.WithMinInterval(time.Second),
-		backoff.WithMaxInterval(defaultMaxInterval),
-		backoff.WithJitterFactor(defaultJitter),
-		backoff.WithMaxRetries(defaultCooldown),
-	)
+	dedupe := srv.NewDedupeCache(viper.GetInt("dedupe-cache-size"))
+
+	if bucket := viper.GetString("dedupe-kv-bucket"); bucket != "" && processDevMode {
+		logger.Warnw("dev mode enabled, ignoring dedupe kv bucket; duplicates are only tracked in memory", "bucket", bucket)
+	} else if bucket != "" {
+		kv, err := srv.NewDedupeKV(conn, bucket, viper.GetDuration("dedupe-ttl"))
+		if err != nil {
+			logger.Fatalw("failed to initialize dedupe kv bucket", "error", err, "bucket", bucket)
+		}
+
+		dedupe = srv.NewKVDedupeCache(viper.GetInt("dedupe-cache-size"), kv)
+	}
+
+	outbox := srv.NewStatusOutbox()
+
+	// the bucket has a default, dev mode only warns when it was configured
+	if bucket := viper.GetString("status-outbox.kv-bucket"); processDevMode && viper.IsSet("status-outbox.kv-bucket") {
+		logger.Warnw("dev mode enabled, ignoring status outbox kv bucket; pending status updates are only kept in memory", "bucket", bucket)
+	} else if !processDevMode {
+		kv, err := srv.NewOutboxKV(conn, bucket)
+		if err != nil {
+			logger.Fatalw("failed to initialize status outbox kv bucket", "error", err, "bucket", bucket)
+		}
+
+		if outbox, err = srv.NewKVStatusOutbox(kv); err != nil {
+			logger.Fatalw("failed to load pending status updates", "error", err, "bucket", bucket)
+		}
+	}
+
+	outbox.Interval = viper.GetDuration("status-outbox.interval")
+	outbox.MinBackoff = viper.GetDuration("status-outbox.min-backoff")
+	outbox.MaxBackoff = viper.GetDuration("status-outbox.max-backoff")
+	outbox.MaxPending = viper.GetInt("status-outbox.max-pending")
+
+	audit, err := newAuditLog(conn)
+	if err != nil {
+		logger.Fatalw("failed to initialize audit log", "error", err)
+	}
+
+	defer func() {
+		if err := audit.Close(); err != nil {
+			logger.Warnw("failed to close audit log", "error", err)
+		}
+	}()
+
+	broadcaster, recorder, err := srv.NewEventRecorder(client)
+	if err != nil {
+		logger.Fatalw("failed to create kubernetes event recorder", "error", err)
+	}
+
+	defer broadcaster.Shutdown()
+
+	clusters, err := srv.NewClusters(ctx, client, config.AppConfig.Clusters)
+	if err != nil {
+		logger.Fatalw("failed to load deployment clusters", "error", err)
+	}
+
+	for _, c := range clusters {
+		defer c.Shutdown()
+	}
 
 	server := &srv.Server{
-		BackoffConfig:    backoffPolicy,
-		Echo:             eSrv,
-		Chart:            chart,
-		EventsConnection: conn,
-		Context:          cx,
-		Debug:            viper.GetBool("logging.debug"),
-		KubeClient:       client,
-		Logger:           logger,
-		EventTopics:      viper.GetStringSlice("event-topics"),
-		ChangeTopics:     viper.GetStringSlice("change-topics"),
-		ValuesPath:       viper.GetString("chart-values-path"),
-		Locations:        viper.GetStringSlice("event-locations"),
-		MetricsPort:      viper.GetInt("loadbalancer-metrics-port"),
+		EventRecorder:      recorder,
+		Audit:              audit,
+		DeploymentStatus:   viper.GetBool("deployment-status"),
+		RetryPolicies:      srv.NewRetryPolicies(config.AppConfig.Retry),
+		LookupRetry:        config.AppConfig.Retry.APILookup,
+		Dedupe:             dedupe,
+		Outbox:             outbox,
+		Echo:               eSrv,
+		Chart:              chart,
+		EventsConnection:   conn,
+		Context:            cx,
+		Debug:              viper.GetBool("logging.debug"),
+		KubeClient:         client,
+		SupergraphEndpoint: viper.GetString("supergraph-endpoint"),
+		Clusters:           clusters,
+		Logger:             logger,
+		LogLevels:          logLevels,
+		EventTopics:        viper.GetStringSlice("event-topics"),
+		ChangeTopics:       viper.GetStringSlice("change-topics"),
+		ValuesPath:         viper.GetString("chart-values-path"),
+		Locations:          viper.GetStringSlice("event-locations"),
+		MetricsPort:        viper.GetInt("loadbalancer-metrics-port"),
+		Timeouts:           config.AppConfig.Timeouts,
+		InventoryInterval:  viper.GetDuration("inventory-interval"),
+		Namespace:          config.AppConfig.Namespace,
 
 		ContainerPortKey: viper.GetString("helm-containerport-key"),
 		ServicePortKey:   viper.GetString("helm-serviceport-key"),
@@ -174,15 +355,47 @@ func process(ctx context.Context, logger *zap.SugaredLogger) error {
 		}
 
 		oauthHTTPClient := oauth2x.NewClient(ctx, oidcTS)
+		oauthHTTPClient.Transport = otelhttp.NewTransport(oauthHTTPClient.Transport)
 		server.APIClient = lbapi.NewClient(viper.GetString("supergraph-endpoint"), lbapi.WithHTTPClient(oauthHTTPClient))
 		server.IPAMClient = ipamclient.NewClient(viper.GetString("supergraph-endpoint"), ipamclient.WithHTTPClient(oauthHTTPClient))
 		server.MetadataClient = metadata.New(config.AppConfig.Metadata.Endpoint,
 			metadata.WithHTTPClient(oauthHTTPClient),
 		)
 	} else {
-		server.APIClient = lbapi.NewClient(viper.GetString("supergraph-endpoint"))
-		server.IPAMClient = ipamclient.NewClient(viper.GetString("supergraph-endpoint"))
-		server.MetadataClient = metadata.New(config.AppConfig.Metadata.Endpoint)
+		httpClient := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+		server.APIClient = lbapi.NewClient(viper.GetString("supergraph-endpoint"), lbapi.WithHTTPClient(httpClient))
+		server.IPAMClient = ipamclient.NewClient(viper.GetString("supergraph-endpoint"), ipamclient.WithHTTPClient(httpClient))
+		server.MetadataClient = metadata.New(config.AppConfig.Metadata.Endpoint, metadata.WithHTTPClient(httpClient))
+	}
+
+	// messages are published through the dev endpoints of the in-memory connection
+	if mem, ok := conn.(*memevents.Connection); ok {
+		server.DevPublisher = mem
+	}
+
+	switch {
+	case processDevMode:
+		// dev mode always serves the admin API without authentication
+		server.AdminInsecure = true
+
+		logger.Warnw("dev mode enabled, the admin API is not authenticated", "ignoredIssuer", config.AppConfig.OIDC.Client.Issuer)
+	case config.AppConfig.OIDC.Client.Issuer != "":
+		server.AdminAuth, err = echojwtx.NewAuth(ctx, echojwtx.AuthConfig{
+			Issuer:   config.AppConfig.OIDC.Client.Issuer,
+			Audience: config.AppConfig.OIDC.Audience,
+		}, echojwtx.WithLogger(logger.Desugar()))
+		if err != nil {
+			logger.Fatalw("failed to initialize admin API authentication", "error", err)
+		}
+	default:
+		server.AdminInsecure = viper.GetBool("admin-insecure")
+
+		if server.AdminInsecure {
+			logger.Warnw("no OIDC issuer configured, the admin API is not authenticated")
+		} else {
+			logger.Warnw("no OIDC issuer configured, the admin API is disabled")
+		}
 	}
 
 	err = otelx.InitTracer(config.AppConfig.Tracing, appName, logger)
@@ -235,9 +448,54 @@ func validateFlags() error {
 		return errRequiredTopics
 	}
 
+	if viper.GetString("audit.path") != "" && viper.GetString("audit.subject") != "" {
+		return errAuditDestination
+	}
+
+	// undelivered status updates would be lost on restart without a bucket
+	if !processDevMode && viper.GetString("status-outbox.kv-bucket") == "" {
+		return errOutboxBucket
+	}
+
+	// the in-memory events connection used by dev mode has no JetStream to publish to
+	if processDevMode && viper.GetString("audit.subject") != "" {
+		return errAuditDevMode
+	}
+
 	return nil
 }
 
+// newEventsConnection returns the configured events connection. Dev mode uses an in-memory
+// connection instead so the operator runs without a broker.
+func newEventsConnection(logger *zap.SugaredLogger) (events.Connection, error) {
+	if !processDevMode {
+		return events.NewConnection(config.AppConfig.Events, events.WithLogger(logger))
+	}
+
+	logger.Warnw("dev mode enabled, using an in-memory events connection; publish messages to /dev/changes/:topic and /dev/events/:topic")
+
+	conn := memevents.NewConnection()
+	conn.SubscribePrefix = config.AppConfig.Events.NATS.SubscribePrefix
+	conn.PublishPrefix = config.AppConfig.Events.NATS.PublishPrefix
+	conn.MessageSource = config.AppConfig.Events.NATS.Source
+	conn.AckWait = devAckWait
+
+	return conn, nil
+}
+
+// newAuditLog returns the configured audit log, or nil when auditing is disabled
+func newAuditLog(conn events.Connection) (*srv.AuditLog, error) {
+	if path := viper.GetString("audit.path"); path != "" {
+		return srv.NewFileAuditLog(path)
+	}
+
+	if subject := viper.GetString("audit.subject"); subject != "" {
+		return srv.NewEventsAuditLog(conn, subject)
+	}
+
+	return nil, nil
+}
+
 func loadHelmChart(chartPath string) (*chart.Chart, error) {
 	chart, err := loader.Load(chartPath)
 	if err != nil {
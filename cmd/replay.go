@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+	"go.infratographer.com/load-balancer-operator/internal/srv"
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay change and event messages from a file.",
+	Long: `Replay change and event messages from a JSON lines file without an events connection.
+Each line holds a change or an event message, {"change": {...}} or {"event": {...}}.
+Loadbalancers are looked up from fixtures when provided. Settings that are not passed
+as flags are read from the config file and environment like the process command.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return replay(cmd, logger)
+	},
+}
+
+var (
+	replayFile     string
+	replayFixtures string
+	replayDryRun   bool
+)
+
+func init() {
+	replayCmd.Flags().StringVar(&replayFile, "file", "", "JSON lines file of the messages to replay")
+	replayCmd.Flags().StringVar(&replayFixtures, "fixtures", "", "JSON file with an array of loadbalancers used to answer load-balancer-api lookups instead of the supergraph")
+	replayCmd.Flags().BoolVar(&replayDryRun, "dry-run", false, "report the handler of each message without changing anything")
+
+	replayCmd.Flags().String("kube-config-path", "", "path to the kubeconfig of the cluster messages are replayed against")
+	replayCmd.Flags().String("chart-path", "", "path that contains deployment chart")
+	replayCmd.Flags().String("chart-values-path", "", "path that contains values file to configure deployment chart")
+	replayCmd.Flags().StringSlice("event-locations", nil, "location id(s) to filter events for")
+
+	rootCmd.AddCommand(replayCmd)
+}
+
+func replay(cmd *cobra.Command, logger *zap.SugaredLogger) error {
+	if replayFile == "" {
+		return errReplayFile
+	}
+
+	ctx := cmd.Context()
+
+	// statuses of replayed messages are logged rather than written to the metadata service
+	config.AppConfig.Metadata.Endpoint = ""
+
+	apiClient, err := replayAPIClient()
+	if err != nil {
+		return err
+	}
+
+	locations := viper.GetStringSlice("event-locations")
+	if cmd.Flags().Changed("event-locations") {
+		locations, _ = cmd.Flags().GetStringSlice("event-locations")
+	}
+
+	server := &srv.Server{
+		APIClient:        apiClient,
+		RetryPolicies:    srv.NewRetryPolicies(config.AppConfig.Retry),
+		DeploymentStatus: viper.GetBool("deployment-status"),
+		Context:          ctx,
+		Logger:           logger,
+		LogLevels:        logLevels,
+		ValuesPath:       flagOrConfig(cmd, "chart-values-path"),
+		Locations:        locations,
+		MetricsPort:      viper.GetInt("loadbalancer-metrics-port"),
+		Timeouts:         config.AppConfig.Timeouts,
+		Namespace:        config.AppConfig.Namespace,
+		ContainerPortKey: viper.GetString("helm-containerport-key"),
+		ServicePortKey:   viper.GetString("helm-serviceport-key"),
+	}
+
+	// a dry run does not need a cluster or chart
+	if !replayDryRun {
+		shutdown, err := replayDeployment(ctx, cmd, server)
+		if err != nil {
+			return err
+		}
+
+		defer shutdown()
+	}
+
+	f, err := os.Open(replayFile)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	results, err := server.Replay(ctx, f, replayDryRun)
+
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if result.Failed() {
+			return fmt.Errorf("%w: line %d: %s", errReplayFailed, result.Line, result.Error)
+		}
+	}
+
+	return nil
+}
+
+// replayAPIClient returns a client answering lookups from the fixtures, or the supergraph
+// client when no fixtures are provided
+func replayAPIClient() (*lbapi.Client, error) {
+	if replayFixtures == "" {
+		return lbapi.NewClient(viper.GetString("supergraph-endpoint")), nil
+	}
+
+	f, err := os.Open(replayFixtures)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	fixtures, err := srv.LoadFixtures(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return srv.NewFixtureClient(fixtures), nil
+}
+
+// replayDeployment configures the clusters and chart that replayed messages are deployed
+// with. The returned function stops recording events to the clusters.
+func replayDeployment(ctx context.Context, cmd *cobra.Command, server *srv.Server) (func(), error) {
+	chartPath := flagOrConfig(cmd, "chart-path")
+	if chartPath == "" {
+		return nil, errChartPath
+	}
+
+	client, err := newKubeAuth(flagOrConfig(cmd, "kube-config-path"))
+	if err != nil {
+		return nil, err
+	}
+
+	server.KubeClient = srv.InstrumentConfig(client)
+
+	if server.Chart, err = loadHelmChart(chartPath); err != nil {
+		return nil, err
+	}
+
+	broadcaster, recorder, err := srv.NewEventRecorder(server.KubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	server.EventRecorder = recorder
+
+	server.Clusters, err = srv.NewClusters(ctx, server.KubeClient, config.AppConfig.Clusters)
+	if err != nil {
+		broadcaster.Shutdown()
+		return nil, err
+	}
+
+	return func() {
+		for _, c := range server.Clusters {
+			c.Shutdown()
+		}
+
+		broadcaster.Shutdown()
+	}, nil
+}
+
+// flagOrConfig returns the value of a flag when it is set, or the setting of the same name
+func flagOrConfig(cmd *cobra.Command, name string) string {
+	if cmd.Flags().Changed(name) {
+		value, _ := cmd.Flags().GetString(name)
+
+		return value
+	}
+
+	return viper.GetString(name)
+}
@@ -16,14 +16,16 @@ import (
 	"go.infratographer.com/x/otelx"
 
 	"go.infratographer.com/load-balancer-operator/internal/config"
+	"go.infratographer.com/load-balancer-operator/internal/srv"
 
 	"go.infratographer.com/x/events"
 	"go.infratographer.com/x/viperx"
 )
 
 var (
-	cfgFile string
-	logger  *zap.SugaredLogger
+	cfgFile   string
+	logger    *zap.SugaredLogger
+	logLevels *srv.LogLevels
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -86,7 +88,12 @@ func initConfig() {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 
-	logger = loggingx.InitLogger(appName, config.AppConfig.Logging)
+	// the logger is built at the debug level so that debug logging can be enabled for
+	// single loadbalancers at runtime, the configured level is applied on top
+	logCfg := config.AppConfig.Logging
+	logCfg.Debug = true
+
+	logger, logLevels = srv.NewLeveledLogger(loggingx.InitLogger(appName, logCfg), config.AppConfig.Logging.Debug)
 }
 
 // setupAppConfig loads our config.AppConfig struct with the values bound by
@@ -2,6 +2,8 @@
 package config
 
 import (
+	"time"
+
 	"go.infratographer.com/x/gidx"
 	"go.infratographer.com/x/oauth2x"
 
@@ -13,12 +15,16 @@ import (
 
 // AppConfig contains the application configuration structure.
 var AppConfig struct {
-	Logging  loggingx.Config
-	Events   events.Config
-	Server   echox.Config
-	Tracing  otelx.Config
-	OIDC     OIDCClientConfig
-	Metadata MetadataConfig
+	Logging   loggingx.Config
+	Events    events.Config
+	Server    echox.Config
+	Tracing   otelx.Config
+	OIDC      OIDCClientConfig
+	Metadata  MetadataConfig
+	Timeouts  TimeoutConfig
+	Retry     RetryConfig
+	Namespace NamespaceConfig
+	Clusters  []ClusterConfig
 }
 
 // MetadataConfig stores the configuration for metadata
@@ -28,7 +34,90 @@ type MetadataConfig struct {
 	Source            string
 }
 
-// OIDCClientConfig stores the configuration for an OIDC client
+// TimeoutConfig stores the timeouts applied to each phase of processing a load balancer.
+// A zero value disables the timeout for that phase.
+type TimeoutConfig struct {
+	APILookup time.Duration `mapstructure:"api-lookup"`
+	Namespace time.Duration
+	Helm      time.Duration
+	Metadata  time.Duration
+	// NamespaceTermination bounds how long a deleted namespace may take to terminate
+	// before it is reported as stuck. A zero value does not wait for termination.
+	NamespaceTermination time.Duration `mapstructure:"namespace-termination"`
+}
+
+// RetryConfig stores the retry policy for each operation the operator performs
+type RetryConfig struct {
+	APILookup       RetryPolicyConfig `mapstructure:"api-lookup"`
+	Install         RetryPolicyConfig
+	Upgrade         RetryPolicyConfig
+	Uninstall       RetryPolicyConfig
+	NamespaceDelete RetryPolicyConfig `mapstructure:"namespace-delete"`
+	MetadataUpdate  RetryPolicyConfig `mapstructure:"metadata-update"`
+}
+
+// RetryPolicyConfig stores an exponential backoff retry policy. Operations are
+// attempted once when MaxRetries is zero.
+type RetryPolicyConfig struct {
+	MaxRetries  int           `mapstructure:"max-retries"`
+	MinInterval time.Duration `mapstructure:"min-interval"`
+	MaxInterval time.Duration `mapstructure:"max-interval"`
+	Jitter      float64
+}
+
+// NamespaceConfig stores the metadata applied to every load balancer namespace
+type NamespaceConfig struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	PodSecurity PodSecurityConfig `mapstructure:"pod-security"`
+
+	// ResourceQuota is the hard limit for each resource in the namespace. No quota is
+	// applied when empty.
+	ResourceQuota map[string]string `mapstructure:"resource-quota"`
+	LimitRange    LimitRangeConfig  `mapstructure:"limit-range"`
+	// NetworkPolicy applies a default-deny ingress policy that only allows TCP traffic to
+	// the load balancer ports and the metrics port
+	NetworkPolicy bool `mapstructure:"network-policy"`
+}
+
+// LimitRangeConfig stores the container limits applied to load balancer namespaces.
+// No limit range is applied when every field is empty.
+type LimitRangeConfig struct {
+	Default        map[string]string
+	DefaultRequest map[string]string `mapstructure:"default-request"`
+	Max            map[string]string
+}
+
+// PodSecurityConfig stores the Pod Security Admission levels applied to load balancer
+// namespaces. An empty level leaves the corresponding label unset.
+type PodSecurityConfig struct {
+	Enforce string
+	Audit   string
+	Warn    string
+	Version string
+}
+
+// ClusterConfig stores a cluster that load balancers are deployed to for a set of
+// locations. A list is used rather than a map keyed by location because config keys
+// are case insensitive while location IDs are not.
+type ClusterConfig struct {
+	Name           string
+	Locations      []string
+	KubeconfigPath string `mapstructure:"kubeconfig-path"`
+	Context        string
+	Secret         SecretRefConfig
+}
+
+// SecretRefConfig references a kubeconfig stored in a secret of the cluster the operator runs in
+type SecretRefConfig struct {
+	Namespace string
+	Name      string
+	Key       string
+}
+
+// OIDCClientConfig stores the configuration for an OIDC client. Tokens presented to the
+// admin API must be issued by the client issuer for Audience.
 type OIDCClientConfig struct {
-	Client oauth2x.Config
+	Client   oauth2x.Config
+	Audience string
 }
@@ -0,0 +1,225 @@
+package memevents
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.infratographer.com/x/events"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Connection is an events.Connection that delivers messages published on it to its own
+// subscribers. Subjects are built and matched like the NATS connection, so the topics
+// configured for NATS can be used unchanged. Like a JetStream consumer, every subscription
+// receives its own copy of a message and redelivers it until it is acked or terminated.
+type Connection struct {
+	// SubscribePrefix is prepended to the subjects of subscriptions
+	SubscribePrefix string
+	// PublishPrefix is prepended to the subjects of published messages
+	PublishPrefix string
+	// MessageSource is set as the source of published messages when it is not empty
+	MessageSource string
+	// AckWait is how long a delivery may remain unacknowledged before the message is
+	// redelivered. Unacknowledged messages are not redelivered when it is zero.
+	AckWait time.Duration
+	// MaxDeliver limits the number of deliveries of a message. Zero is unlimited.
+	MaxDeliver uint64
+
+	mu      sync.Mutex
+	closed  bool
+	seq     uint64
+	changes []*subscription[events.ChangeMessage]
+	events  []*subscription[events.EventMessage]
+}
+
+var _ events.Connection = (*Connection)(nil)
+
+// NewConnection returns an in-memory connection without subscribers
+func NewConnection() *Connection {
+	return &Connection{}
+}
+
+// Shutdown stops all subscriptions and closes their channels
+func (c *Connection) Shutdown(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	for _, sub := range c.changes {
+		sub.cancel()
+	}
+
+	for _, sub := range c.events {
+		sub.cancel()
+	}
+
+	c.changes = nil
+	c.events = nil
+
+	return nil
+}
+
+// Source returns the connection itself as there is no underlying connection
+func (c *Connection) Source() any {
+	return c
+}
+
+// SubscribeChanges subscribes to the change messages published to topic
+func (c *Connection) SubscribeChanges(ctx context.Context, topic string) (<-chan events.Message[events.ChangeMessage], error) {
+	return subscribe(ctx, c, &c.changes, buildSubject(c.SubscribePrefix, "changes", topic))
+}
+
+// SubscribeEvents subscribes to the event messages published to topic
+func (c *Connection) SubscribeEvents(ctx context.Context, topic string) (<-chan events.Message[events.EventMessage], error) {
+	return subscribe(ctx, c, &c.events, buildSubject(c.SubscribePrefix, "events", topic))
+}
+
+// PublishChange delivers a change message to the subscriptions matching its subject
+func (c *Connection) PublishChange(ctx context.Context, topic string, message events.ChangeMessage) (events.Message[events.ChangeMessage], error) {
+	if err := message.Validate(); err != nil {
+		return nil, err
+	}
+
+	message.TraceContext = traceContext(ctx)
+
+	if c.MessageSource != "" {
+		message.Source = c.MessageSource
+	}
+
+	return publish(c, &c.changes, buildSubject(c.PublishPrefix, "changes", message.EventType, topic), message)
+}
+
+// PublishEvent delivers an event message to the subscriptions matching its subject
+func (c *Connection) PublishEvent(ctx context.Context, topic string, message events.EventMessage) (events.Message[events.EventMessage], error) {
+	if err := message.Validate(); err != nil {
+		return nil, err
+	}
+
+	message.TraceContext = traceContext(ctx)
+
+	if c.MessageSource != "" {
+		message.Source = c.MessageSource
+	}
+
+	return publish(c, &c.events, buildSubject(c.PublishPrefix, "events", message.EventType, topic), message)
+}
+
+// SubscribeAuthRelationshipRequests is not supported by the in-memory connection
+func (c *Connection) SubscribeAuthRelationshipRequests(_ context.Context, _ string) (<-chan events.Request[events.AuthRelationshipRequest, events.AuthRelationshipResponse], error) {
+	return nil, ErrUnsupported
+}
+
+// PublishAuthRelationshipRequest is not supported by the in-memory connection
+func (c *Connection) PublishAuthRelationshipRequest(_ context.Context, _ string, _ events.AuthRelationshipRequest) (events.Message[events.AuthRelationshipResponse], error) {
+	return nil, ErrUnsupported
+}
+
+// Pending returns the number of messages that were not acked or terminated by a subscriber
+func (c *Connection) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := 0
+
+	for _, sub := range c.changes {
+		pending += sub.pending()
+	}
+
+	for _, sub := range c.events {
+		pending += sub.pending()
+	}
+
+	return pending
+}
+
+func subscribe[T any](ctx context.Context, c *Connection, subs *[]*subscription[T], subject string) (<-chan events.Message[T], error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrConnectionClosed
+	}
+
+	sub := newSubscription[T](ctx, c, subject)
+	*subs = append(*subs, sub)
+
+	go sub.run()
+
+	return sub.out, nil
+}
+
+func publish[T any](c *Connection, subs *[]*subscription[T], subject string, msg T) (events.Message[T], error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrConnectionClosed
+	}
+
+	c.seq++
+
+	e := &entry[T]{
+		seq:       c.seq,
+		subject:   subject,
+		message:   msg,
+		published: time.Now(),
+	}
+
+	active := (*subs)[:0]
+
+	for _, sub := range *subs {
+		if sub.ctx.Err() != nil {
+			continue
+		}
+
+		active = append(active, sub)
+
+		if matchSubject(sub.subject, subject) {
+			sub.add(e.copy())
+		}
+	}
+
+	*subs = active
+
+	return &message[T]{conn: c, entry: e}, nil
+}
+
+// traceContext carries the trace of ctx to subscribers like the NATS connection
+func traceContext(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	return carrier
+}
+
+func buildSubject(prefix string, parts ...string) string {
+	if prefix != "" {
+		parts = append([]string{prefix}, parts...)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// matchSubject reports whether subject matches pattern using NATS wildcards, where *
+// matches a single token and > matches one or more trailing tokens
+func matchSubject(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(subjectTokens) > i
+		}
+
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+
+	return len(patternTokens) == len(subjectTokens)
+}
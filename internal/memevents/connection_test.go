@@ -0,0 +1,175 @@
+package memevents
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/events"
+)
+
+const testTimeout = time.Second
+
+func receive[T any](t *testing.T, ch <-chan events.Message[T]) events.Message[T] {
+	t.Helper()
+
+	select {
+	case msg, ok := <-ch:
+		require.True(t, ok, "subscription closed")
+		return msg
+	case <-time.After(testTimeout):
+		require.FailNow(t, "no message received")
+	}
+
+	return nil
+}
+
+func assertNothing[T any](t *testing.T, ch <-chan events.Message[T], wait time.Duration) {
+	t.Helper()
+
+	select {
+	case msg := <-ch:
+		assert.Failf(t, "unexpected message", "%v", msg)
+	case <-time.After(wait):
+	}
+}
+
+func TestMatchSubject(t *testing.T) {
+	type testCase struct {
+		pattern string
+		subject string
+		match   bool
+	}
+
+	testCases := []testCase{
+		{pattern: "changes.*.load-balancer", subject: "changes.create.load-balancer", match: true},
+		{pattern: "changes.*.load-balancer", subject: "changes.create.load-balancer-port", match: false},
+		{pattern: "changes.*.load-balancer", subject: "changes.create.load-balancer.extra", match: false},
+		{pattern: "changes.>", subject: "changes.update.load-balancer", match: true},
+		{pattern: "changes.>", subject: "changes", match: false},
+		{pattern: "events.ip-address.assigned.*", subject: "events.ip-address.unassigned.ip-address", match: false},
+		{pattern: "com.infratographer.events.*", subject: "com.infratographer.events.ip-address", match: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.subject, func(t *testing.T) {
+			assert.Equal(t, tc.match, matchSubject(tc.pattern, tc.subject))
+		})
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	conn := NewConnection()
+	conn.SubscribePrefix = "com.infratographer"
+	conn.PublishPrefix = "com.infratographer"
+	conn.MessageSource = "test"
+
+	ctx := context.Background()
+
+	lbs, err := conn.SubscribeChanges(ctx, "*.load-balancer")
+	require.NoError(t, err)
+
+	all, err := conn.SubscribeChanges(ctx, ">")
+	require.NoError(t, err)
+
+	_, err = conn.PublishChange(ctx, "load-balancer", events.ChangeMessage{})
+	assert.Error(t, err, "invalid messages should not be published")
+
+	published, err := conn.PublishChange(ctx, "load-balancer", events.ChangeMessage{SubjectID: "loadbal-test", EventType: "create"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, published.Ack(), ErrNotDelivered)
+
+	_, err = conn.PublishChange(ctx, "load-balancer-port", events.ChangeMessage{SubjectID: "loadprt-test", EventType: "update"})
+	require.NoError(t, err)
+
+	msg := receive(t, lbs)
+	assert.Equal(t, "com.infratographer.changes.create.load-balancer", msg.Topic())
+	assert.Equal(t, "loadbal-test", msg.Message().SubjectID.String())
+	assert.Equal(t, "test", msg.Message().Source)
+	assert.Equal(t, uint64(1), msg.Deliveries())
+	assert.Equal(t, published.ID(), msg.ID())
+	require.NoError(t, msg.Ack())
+	assert.ErrorIs(t, msg.Ack(), ErrAlreadyAcknowledged)
+
+	assertNothing(t, lbs, 50*time.Millisecond)
+
+	// every subscription receives its own copy
+	assert.Equal(t, "loadbal-test", receive(t, all).Message().SubjectID.String())
+	assert.Equal(t, "loadprt-test", receive(t, all).Message().SubjectID.String())
+	assert.Equal(t, 2, conn.Pending(), "messages should remain pending until they are acked")
+}
+
+func TestRedelivery(t *testing.T) {
+	ctx := context.Background()
+	msg := events.EventMessage{SubjectID: "ipamipa-test", EventType: "ip-address.assigned"}
+
+	t.Run("nak", func(t *testing.T) {
+		conn := NewConnection()
+
+		ch, err := conn.SubscribeEvents(ctx, "ip-address.assigned.*")
+		require.NoError(t, err)
+
+		_, err = conn.PublishEvent(ctx, "ip-address", msg)
+		require.NoError(t, err)
+
+		first := receive(t, ch)
+		require.NoError(t, first.Nak(0))
+
+		second := receive(t, ch)
+		assert.Equal(t, first.ID(), second.ID())
+		assert.Equal(t, uint64(2), second.Deliveries())
+
+		require.NoError(t, second.Nak(50*time.Millisecond))
+		assertNothing(t, ch, 10*time.Millisecond)
+
+		third := receive(t, ch)
+		assert.Equal(t, uint64(3), third.Deliveries())
+		require.NoError(t, third.Term())
+
+		assertNothing(t, ch, 50*time.Millisecond)
+		assert.Equal(t, 0, conn.Pending())
+	})
+
+	t.Run("ack wait", func(t *testing.T) {
+		conn := NewConnection()
+		conn.AckWait = 20 * time.Millisecond
+		conn.MaxDeliver = 2
+
+		ch, err := conn.SubscribeEvents(ctx, ">")
+		require.NoError(t, err)
+
+		_, err = conn.PublishEvent(ctx, "ip-address", msg)
+		require.NoError(t, err)
+
+		assert.Equal(t, uint64(1), receive(t, ch).Deliveries())
+		assert.Equal(t, uint64(2), receive(t, ch).Deliveries(), "unacknowledged messages should be redelivered")
+
+		assertNothing(t, ch, 50*time.Millisecond)
+		assert.Equal(t, 0, conn.Pending(), "messages should be dropped after the maximum deliveries")
+	})
+}
+
+func TestShutdown(t *testing.T) {
+	conn := NewConnection()
+	ctx := context.Background()
+
+	ch, err := conn.SubscribeChanges(ctx, ">")
+	require.NoError(t, err)
+
+	require.NoError(t, conn.Shutdown(ctx))
+
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok, "subscriptions should be closed")
+	case <-time.After(testTimeout):
+		assert.Fail(t, "subscription not closed")
+	}
+
+	_, err = conn.SubscribeChanges(ctx, ">")
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+
+	_, err = conn.PublishChange(ctx, "load-balancer", events.ChangeMessage{SubjectID: "loadbal-test", EventType: "create"})
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+}
@@ -0,0 +1,2 @@
+// Package memevents provides an in-memory events connection for tests and local development
+package memevents
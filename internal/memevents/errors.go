@@ -0,0 +1,17 @@
+package memevents
+
+import "errors"
+
+var (
+	// ErrConnectionClosed is returned when publishing or subscribing after the connection is shut down
+	ErrConnectionClosed = errors.New("connection closed")
+
+	// ErrUnsupported is returned for the message types the connection does not handle
+	ErrUnsupported = errors.New("not supported by the in-memory connection")
+
+	// ErrAlreadyAcknowledged is returned when a delivery is acknowledged more than once
+	ErrAlreadyAcknowledged = errors.New("message already acknowledged")
+
+	// ErrNotDelivered is returned when acknowledging a message that was published rather than delivered
+	ErrNotDelivered = errors.New("message was not delivered to a subscriber")
+)
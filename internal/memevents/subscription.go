@@ -0,0 +1,253 @@
+package memevents
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.infratographer.com/x/events"
+)
+
+// entry is a published message as tracked by a subscription
+type entry[T any] struct {
+	seq        uint64
+	subject    string
+	message    T
+	published  time.Time
+	deliveries uint64
+	done       bool
+	timer      *time.Timer
+}
+
+// copy returns an undelivered copy of the entry for a subscription
+func (e *entry[T]) copy() *entry[T] {
+	return &entry[T]{
+		seq:       e.seq,
+		subject:   e.subject,
+		message:   e.message,
+		published: e.published,
+	}
+}
+
+// subscription delivers the messages matching its subject in the order they are queued
+type subscription[T any] struct {
+	conn    *Connection
+	subject string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	out     chan events.Message[T]
+	wake    chan struct{}
+
+	mu      sync.Mutex
+	queue   []*entry[T]
+	entries map[uint64]*entry[T]
+}
+
+func newSubscription[T any](ctx context.Context, c *Connection, subject string) *subscription[T] {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &subscription[T]{
+		conn:    c,
+		subject: subject,
+		ctx:     ctx,
+		cancel:  cancel,
+		out:     make(chan events.Message[T]),
+		wake:    make(chan struct{}, 1),
+		entries: make(map[uint64]*entry[T]),
+	}
+}
+
+// run sends queued messages to the subscriber until the subscription is cancelled
+func (s *subscription[T]) run() {
+	defer close(s.out)
+
+	for {
+		msg, ok := s.next()
+		if !ok {
+			return
+		}
+
+		select {
+		case s.out <- msg:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// next waits for a queued message and returns its next delivery
+func (s *subscription[T]) next() (*message[T], bool) {
+	for {
+		s.mu.Lock()
+
+		for len(s.queue) > 0 {
+			e := s.queue[0]
+			s.queue = s.queue[1:]
+
+			if e.done {
+				continue
+			}
+
+			msg := s.deliver(e)
+			s.mu.Unlock()
+
+			return msg, true
+		}
+
+		s.mu.Unlock()
+
+		select {
+		case <-s.wake:
+		case <-s.ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// deliver counts a delivery of e and starts waiting for its acknowledgement. s.mu must be held.
+func (s *subscription[T]) deliver(e *entry[T]) *message[T] {
+	e.deliveries++
+	delivery := e.deliveries
+
+	if wait := s.conn.AckWait; wait > 0 {
+		e.timer = time.AfterFunc(wait, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			// the delivery was settled or the message delivered again in the meantime
+			if e.done || e.deliveries != delivery {
+				return
+			}
+
+			s.redeliver(e, 0)
+		})
+	}
+
+	return &message[T]{conn: s.conn, sub: s, entry: e, delivery: delivery}
+}
+
+// add queues a newly published message
+func (s *subscription[T]) add(e *entry[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[e.seq] = e
+	s.enqueue(e)
+}
+
+// enqueue queues e for delivery. s.mu must be held.
+func (s *subscription[T]) enqueue(e *entry[T]) {
+	s.queue = append(s.queue, e)
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// redeliver queues e again after delay, or drops it once it reached the maximum number of
+// deliveries. s.mu must be held.
+func (s *subscription[T]) redeliver(e *entry[T], delay time.Duration) {
+	if limit := s.conn.MaxDeliver; limit > 0 && e.deliveries >= limit {
+		s.finish(e)
+		return
+	}
+
+	if delay <= 0 {
+		s.enqueue(e)
+		return
+	}
+
+	e.timer = time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if !e.done {
+			s.enqueue(e)
+		}
+	})
+}
+
+// finish stops tracking e. s.mu must be held.
+func (s *subscription[T]) finish(e *entry[T]) {
+	e.done = true
+
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+
+	delete(s.entries, e.seq)
+}
+
+// settle acks, naks or terminates a delivery of e
+func (s *subscription[T]) settle(e *entry[T], fn func(e *entry[T])) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.done {
+		return ErrAlreadyAcknowledged
+	}
+
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+
+	fn(e)
+
+	return nil
+}
+
+func (s *subscription[T]) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+// message is a delivery of a published message to a subscription. Messages returned when
+// publishing are not delivered and cannot be acknowledged.
+type message[T any] struct {
+	conn     *Connection
+	sub      *subscription[T]
+	entry    *entry[T]
+	delivery uint64
+}
+
+func (m *message[T]) Connection() events.Connection { return m.conn }
+func (m *message[T]) ID() string                    { return strconv.FormatUint(m.entry.seq, 10) }
+func (m *message[T]) Topic() string                 { return m.entry.subject }
+func (m *message[T]) Message() T                    { return m.entry.message }
+func (m *message[T]) Timestamp() time.Time          { return m.entry.published }
+func (m *message[T]) Deliveries() uint64            { return m.delivery }
+func (m *message[T]) Error() error                  { return nil }
+func (m *message[T]) Source() any                   { return nil }
+
+// Ack acknowledges the message so it is not delivered again
+func (m *message[T]) Ack() error {
+	if m.sub == nil {
+		return ErrNotDelivered
+	}
+
+	return m.sub.settle(m.entry, m.sub.finish)
+}
+
+// Nak redelivers the message after delay
+func (m *message[T]) Nak(delay time.Duration) error {
+	if m.sub == nil {
+		return ErrNotDelivered
+	}
+
+	return m.sub.settle(m.entry, func(e *entry[T]) {
+		m.sub.redeliver(e, delay)
+	})
+}
+
+// Term acknowledges the message without processing it so it is not delivered again
+func (m *message[T]) Term() error {
+	if m.sub == nil {
+		return ErrNotDelivered
+	}
+
+	return m.sub.settle(m.entry, m.sub.finish)
+}
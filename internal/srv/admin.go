@@ -0,0 +1,418 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	lbmeta "go.infratographer.com/load-balancer-api/pkg/metadata"
+	"go.infratographer.com/x/gidx"
+)
+
+// lbSummary describes a managed loadbalancer and the work the operator is doing on it
+type lbSummary struct {
+	ID              string       `json:"id"`
+	Cluster         string       `json:"cluster,omitempty"`
+	Namespace       string       `json:"namespace,omitempty"`
+	Location        string       `json:"location,omitempty"`
+	State           string       `json:"state,omitempty"`
+	QueueLength     int          `json:"queueLength"`
+	Paused          pauseMode    `json:"paused,omitempty"`
+	CurrentTask     *taskSummary `json:"currentTask,omitempty"`
+	LastEvent       string       `json:"lastEvent,omitempty"`
+	LastRun         *time.Time   `json:"lastRun,omitempty"`
+	LastError       string       `json:"lastError,omitempty"`
+	ReleaseRevision int          `json:"releaseRevision,omitempty"`
+	ReleaseStatus   string       `json:"releaseStatus,omitempty"`
+	ChartVersion    string       `json:"chartVersion,omitempty"`
+	InventoryStale  bool         `json:"inventoryStale,omitempty"`
+}
+
+// taskSummary describes the task a runner is processing
+type taskSummary struct {
+	Event     string    `json:"event"`
+	SubjectID string    `json:"subjectID"`
+	Started   time.Time `json:"started"`
+}
+
+// lbDetail is an lbSummary with the loadbalancer data last received from the load-balancer-api
+type lbDetail struct {
+	lbSummary
+
+	Name        string   `json:"name,omitempty"`
+	OwnerID     string   `json:"ownerID,omitempty"`
+	Ports       []int64  `json:"ports,omitempty"`
+	IPAddresses []string `json:"ipAddresses,omitempty"`
+}
+
+// adminRoutes registers the operator admin endpoints on the /admin group. Requests are
+// authenticated with AdminAuth, the endpoints are only registered without it when
+// AdminInsecure is set.
+func (s *Server) adminRoutes(g *echo.Group) {
+	if s.AdminAuth == nil && !s.AdminInsecure {
+		return
+	}
+
+	g.Use(s.AdminAuth.Middleware())
+
+	g.GET("/loadbalancers", s.listLoadBalancersHandler)
+	g.GET("/loadbalancers/:id", s.getLoadBalancerHandler)
+	g.GET("/namespaces/terminating", s.terminatingNamespacesHandler)
+	g.GET("/loglevel", s.logLevelHandler)
+	g.POST("/loadbalancers/:id/reconcile", s.reconcileLoadBalancerHandler)
+	g.POST("/loadbalancers/:id/pause", s.pauseLoadBalancerHandler)
+	g.POST("/loadbalancers/:id/resume", s.resumeLoadBalancerHandler)
+	g.PUT("/loglevel/loadbalancers/:id", s.debugLoadBalancerHandler(true))
+	g.DELETE("/loglevel/loadbalancers/:id", s.debugLoadBalancerHandler(false))
+	g.PUT("/loglevel/locations/:id", s.debugLocationHandler(true))
+	g.DELETE("/loglevel/locations/:id", s.debugLocationHandler(false))
+}
+
+// listLoadBalancersHandler lists the managed loadbalancers
+func (s *Server) listLoadBalancersHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{
+		"loadBalancers": s.loadBalancerSummaries(c.Request().Context()),
+		"clusters":      s.inventory.clusterStatuses(),
+	})
+}
+
+// getLoadBalancerHandler returns the details of a managed loadbalancer
+func (s *Server) getLoadBalancerHandler(c echo.Context) error {
+	id := c.Param("id")
+
+	for _, summary := range s.loadBalancerSummaries(c.Request().Context()) {
+		if summary.ID != id {
+			continue
+		}
+
+		detail := lbDetail{lbSummary: summary}
+
+		if r, ok := s.runners()[id]; ok {
+			if lb := r.loadBalancer(); lb != nil && lb.lbData != nil {
+				detail.Name = lb.lbData.Name
+				detail.OwnerID = lb.lbData.Owner.ID
+
+				for _, port := range lb.lbData.Ports.Edges {
+					detail.Ports = append(detail.Ports, port.Node.Number)
+				}
+
+				for _, ip := range lb.lbData.IPAddresses {
+					detail.IPAddresses = append(detail.IPAddresses, ip.IP)
+				}
+			}
+		}
+
+		return c.JSON(http.StatusOK, detail)
+	}
+
+	return echo.NewHTTPError(http.StatusNotFound, "loadbalancer not managed by this operator")
+}
+
+// reconcileLoadBalancerHandler redeploys a loadbalancer from its current data in the
+// load-balancer-api. The task is queued on the runner of the loadbalancer like any other.
+func (s *Server) reconcileLoadBalancerHandler(c echo.Context) error {
+	id, err := loadBalancerIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	if r, ok := s.runners()[id.String()]; ok && r.pauseMode() != pauseNone {
+		return echo.NewHTTPError(http.StatusConflict, "loadbalancer is paused")
+	}
+
+	lb, err := s.newLoadBalancer(c.Request().Context(), id, nil)
+
+	switch {
+	case errors.Is(err, lbapi.ErrLBNotfound):
+		return echo.NewHTTPError(http.StatusNotFound, "loadbalancer not found")
+	case err != nil:
+		return echo.NewHTTPError(http.StatusBadGateway, "unable to look up loadbalancer").SetInternal(err)
+	case !s.managesLocation(lb):
+		return echo.NewHTTPError(http.StatusNotFound, "loadbalancer not managed by this operator")
+	}
+
+	ctx := withCluster(s.taskContext(), s.clusterFor(lb, nil))
+
+	if t := newTask(ctx, s, lb, reconcileEventType, id); !s.checkChannel(ctx, lb).submit(t) {
+		t.cancelTask()
+		return echo.NewHTTPError(http.StatusConflict, "loadbalancer runner stopped")
+	}
+
+	s.Logger.Infow("loadbalancer reconcile requested", "loadBalancer", id.String())
+
+	return c.JSON(http.StatusAccepted, echo.Map{
+		"id":    id.String(),
+		"event": reconcileEventType,
+	})
+}
+
+// pauseLoadBalancerHandler stops processing tasks for a loadbalancer. New tasks are held
+// until it is resumed, or dropped when mode=skip.
+func (s *Server) pauseLoadBalancerHandler(c echo.Context) error {
+	id, err := loadBalancerIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	mode := pauseMode(c.QueryParam("mode"))
+
+	switch mode {
+	case pauseNone:
+		mode = pauseBuffer
+	case pauseBuffer, pauseSkip:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "mode must be buffer or skip")
+	}
+
+	return s.setPause(c, id, mode)
+}
+
+// resumeLoadBalancerHandler resumes processing of a paused loadbalancer, starting with any held tasks
+func (s *Server) resumeLoadBalancerHandler(c echo.Context) error {
+	id, err := loadBalancerIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := s.runners()[id.String()]; !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "loadbalancer is not paused")
+	}
+
+	return s.setPause(c, id, pauseNone)
+}
+
+// setPause changes the pause mode of the runner of a loadbalancer and reports the change
+// in the loadbalancer status. A runner is started for loadbalancers that do not have one
+// so that their events are held as well.
+func (s *Server) setPause(c echo.Context, id gidx.PrefixedID, mode pauseMode) error {
+	ctx := c.Request().Context()
+
+	_, managed := s.runners()[id.String()]
+
+	lb, err := s.newLoadBalancer(ctx, id, nil)
+
+	switch {
+	// without a lookup only loadbalancers that already have a runner are known to be in a managed location
+	case err != nil && !managed && len(s.Locations) > 0:
+		return echo.NewHTTPError(http.StatusBadGateway, "unable to look up loadbalancer").SetInternal(err)
+	case err != nil:
+		s.Logger.Warnw("unable to look up loadbalancer, pause status will not be reported", "error", err, "loadBalancer", id.String())
+
+		lb = &loadBalancer{loadBalancerID: id, lbType: typeLB}
+	case !s.managesLocation(lb):
+		return echo.NewHTTPError(http.StatusNotFound, "loadbalancer not managed by this operator")
+	}
+
+	r := s.checkChannel(s.taskContext(), lb)
+
+	if previous := r.pauseMode(); previous != pauseNone {
+		pausedLoadBalancersGauge.DeleteLabelValues(id.String(), string(previous))
+	}
+
+	r.setPause(mode)
+
+	if mode != pauseNone {
+		pausedLoadBalancersGauge.WithLabelValues(id.String(), string(mode)).Set(1)
+	}
+
+	s.Logger.Infow("loadbalancer pause changed", "loadBalancer", id.String(), "mode", mode, "paused", mode != pauseNone)
+
+	s.reportPause(withCluster(ctx, s.clusterFor(lb, nil)), lb, r, mode != pauseNone)
+
+	return c.JSON(http.StatusOK, r.summary(id.String()))
+}
+
+// managesLocation reports whether the location of a looked up loadbalancer is handled by
+// this operator
+func (s *Server) managesLocation(lb *loadBalancer) bool {
+	return len(s.Locations) == 0 || (lb.lbData != nil && s.locationCheck(gidx.PrefixedID(lb.lbData.Location.ID)))
+}
+
+// reportPause writes the paused flag to the loadbalancer status, keeping its current state
+func (s *Server) reportPause(ctx context.Context, lb *loadBalancer, r *runner, paused bool) {
+	state := lbmeta.LoadBalancerState(r.summary(lb.loadBalancerID.String()).State)
+
+	if state == "" && lb.lbData != nil {
+		t := &lbTask{lb: lb, ctx: ctx, srv: s}
+		if current := t.loadBalancerStatus(); current != nil {
+			state = current.State
+		}
+	}
+
+	if state == "" {
+		s.Logger.Debugw("loadbalancer state unknown, pause status not reported", "loadBalancer", lb.loadBalancerID.String())
+		return
+	}
+
+	reason, message := statusReasonResumed, "processing resumed"
+	if paused {
+		reason, message = statusReasonPaused, "processing paused"
+	}
+
+	status := s.newLoadBalancerStatus(lb, state, reason, message, nil)
+	status.Paused = paused
+
+	if err := s.LoadBalancerStatusUpdate(ctx, lb.loadBalancerID, status); err != nil {
+		s.Logger.Warnw("unable to report pause status", "error", err, "loadBalancer", lb.loadBalancerID.String())
+	}
+}
+
+// logLevelHandler returns the log level and the loadbalancers and locations logged at debug
+func (s *Server) logLevelHandler(c echo.Context) error {
+	if s.LogLevels == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "log levels cannot be changed")
+	}
+
+	return c.JSON(http.StatusOK, s.LogLevels.summary())
+}
+
+// debugLoadBalancerHandler enables or disables debug logging for a loadbalancer
+func (s *Server) debugLoadBalancerHandler(debug bool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id, err := loadBalancerIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		if s.LogLevels == nil {
+			return echo.NewHTTPError(http.StatusNotImplemented, "log levels cannot be changed")
+		}
+
+		s.LogLevels.setLoadBalancerDebug(id.String(), debug)
+		s.Logger.Infow("loadbalancer debug logging changed", "loadBalancer", id.String(), "debug", debug)
+
+		return c.JSON(http.StatusOK, s.LogLevels.summary())
+	}
+}
+
+// debugLocationHandler enables or disables debug logging for the loadbalancers in a location
+func (s *Server) debugLocationHandler(debug bool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id, err := gidx.Parse(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid location id")
+		}
+
+		if s.LogLevels == nil {
+			return echo.NewHTTPError(http.StatusNotImplemented, "log levels cannot be changed")
+		}
+
+		s.LogLevels.setLocationDebug(id.String(), debug)
+		s.Logger.Infow("location debug logging changed", "location", id.String(), "debug", debug)
+
+		return c.JSON(http.StatusOK, s.LogLevels.summary())
+	}
+}
+
+// loadBalancerIDParam returns the loadbalancer ID of the request path
+func loadBalancerIDParam(c echo.Context) (gidx.PrefixedID, error) {
+	id, err := gidx.Parse(c.Param("id"))
+	if err != nil || id.Prefix() != LBPrefix {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid loadbalancer id")
+	}
+
+	return id, nil
+}
+
+// taskContext returns the context tasks submitted through the admin API run with. Tasks
+// outlive the request that submitted them.
+func (s *Server) taskContext() context.Context {
+	if s.Context != nil {
+		return s.Context
+	}
+
+	return context.Background()
+}
+
+// terminatingNamespacesHandler lists the load balancer namespaces that are stuck terminating
+func (s *Server) terminatingNamespacesHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{
+		"namespaces": s.stuckNamespaces.list(),
+	})
+}
+
+// loadBalancerSummaries combines the runner of each loadbalancer with its deployment found
+// by the last inventory resync, ordered by ID
+func (s *Server) loadBalancerSummaries(ctx context.Context) []lbSummary {
+	summaries := map[string]*lbSummary{}
+
+	for id, r := range s.runners() {
+		summary := r.summary(id)
+
+		if lb := r.loadBalancer(); lb != nil {
+			summary.Cluster = clusterName(withCluster(ctx, s.clusterFor(lb, nil)))
+		}
+
+		summaries[id] = &summary
+	}
+
+	for _, m := range s.inventory.list() {
+		summary, ok := summaries[m.ID]
+		if !ok {
+			summary = &lbSummary{ID: m.ID}
+			summaries[m.ID] = summary
+		}
+
+		summary.Cluster = m.Cluster
+		summary.Namespace = m.Namespace
+		summary.ReleaseStatus = m.ReleaseStatus
+		summary.ChartVersion = m.ChartVersion
+		summary.InventoryStale = m.Stale
+
+		if summary.Location == "" {
+			summary.Location = m.Location
+		}
+
+		if summary.ReleaseRevision == 0 {
+			summary.ReleaseRevision = m.ReleaseRevision
+		}
+	}
+
+	out := make([]lbSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		out = append(out, *summary)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+
+	return out
+}
+
+// summary describes the runner of a loadbalancer
+func (r *runner) summary(id string) lbSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	summary := lbSummary{
+		ID:              id,
+		State:           r.state,
+		QueueLength:     r.queued,
+		Paused:          r.pause,
+		LastEvent:       r.lastEvent,
+		LastError:       r.lastError,
+		ReleaseRevision: r.revision,
+	}
+
+	if !r.lastRun.IsZero() {
+		lastRun := r.lastRun
+		summary.LastRun = &lastRun
+	}
+
+	if r.current != nil {
+		summary.CurrentTask = &taskSummary{
+			Event:     r.current.evt,
+			SubjectID: r.current.subj.String(),
+			Started:   r.started,
+		}
+	}
+
+	if r.lb != nil && r.lb.lbData != nil {
+		summary.Location = r.lb.lbData.Location.ID
+	}
+
+	return summary
+}
@@ -0,0 +1,392 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/echojwtx"
+	"go.infratographer.com/x/echox"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+func (suite *srvTestSuite) TestLoadBalancerAdminHandlers() { //nolint:govet
+	e, err := echox.NewServer(zap.NewNop(), echox.Config{}, nil)
+	require.NoError(suite.T(), err, "unexpected error creating new server")
+
+	id := gidx.MustNewID(LBPrefix)
+
+	s := &Server{
+		Echo:          e,
+		LoadBalancers: make(map[string]*runner),
+		AdminInsecure: true,
+		inventory:     newLBInventory(),
+	}
+
+	r := NewRunner(context.TODO(), func(*lbTask) {})
+	defer r.stop()
+
+	r.track(&loadBalancer{loadBalancerID: id, lbData: &lbapi.LoadBalancer{
+		Name:        "web",
+		Location:    lbapi.LocationNode{ID: "lctnloc-one"},
+		IPAddresses: []lbapi.IPAddress{{IP: "192.0.2.1"}},
+		Ports:       lbapi.Ports{Edges: []lbapi.PortEdges{{Node: lbapi.PortNode{Number: 443}}}},
+	}})
+	r.finished(&lbTask{evt: "update", lb: &loadBalancer{revision: 3, state: loadBalancerStateDegraded}, err: errCheckFailed})
+
+	s.LoadBalancers[id.String()] = r
+	s.inventory.update([]managedLoadBalancer{
+		{ID: id.String(), Cluster: "default", Namespace: "lb-one", ReleaseStatus: "failed", ChartVersion: "1.0.0", ReleaseRevision: 3},
+		{ID: "loadbal-untracked", Cluster: "east", Namespace: "lb-two", ReleaseStatus: "deployed", ReleaseRevision: 1},
+	}, map[string]error{"default": nil, "east": nil})
+
+	// the east cluster cannot be reached by the next resync
+	s.inventory.update([]managedLoadBalancer{
+		{ID: id.String(), Cluster: "default", Namespace: "lb-one", ReleaseStatus: "failed", ChartVersion: "1.0.0", ReleaseRevision: 3},
+	}, map[string]error{"default": nil, "east": errCheckFailed})
+
+	s.Echo.AddHandler(s)
+
+	type testCase struct {
+		name     string
+		path     string
+		status   int
+		contains []string
+	}
+
+	testCases := []testCase{
+		{
+			name:   "list",
+			path:   "/admin/loadbalancers",
+			status: http.StatusOK,
+			contains: []string{
+				`"id":"` + id.String() + `"`,
+				`"queueLength":0`,
+				`"lastEvent":"update"`,
+				`"lastError":"check failed"`,
+				`"releaseRevision":3`,
+				`"state":"degraded"`,
+				`"id":"loadbal-untracked"`,
+				`"inventoryStale":true`,
+				`"cluster":"east","lastAttempt"`,
+				`"lastError":"check failed"}`,
+			},
+		},
+		{
+			name:     "detail",
+			path:     "/admin/loadbalancers/" + id.String(),
+			status:   http.StatusOK,
+			contains: []string{`"name":"web"`, `"ports":[443]`, `"ipAddresses":["192.0.2.1"]`, `"namespace":"lb-one"`, `"location":"lctnloc-one"`},
+		},
+		{
+			name:   "unknown",
+			path:   "/admin/loadbalancers/loadbal-unknown",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tcase.path, nil)
+			rec := httptest.NewRecorder()
+			s.Echo.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tcase.status, rec.Code)
+
+			for _, c := range tcase.contains {
+				assert.Contains(t, rec.Body.String(), c)
+			}
+		})
+	}
+
+	// the list is ordered by id
+	req := httptest.NewRequest(http.MethodGet, "/admin/loadbalancers", nil)
+	rec := httptest.NewRecorder()
+	s.Echo.Handler().ServeHTTP(rec, req)
+
+	var body struct {
+		LoadBalancers []lbSummary `json:"loadBalancers"`
+	}
+
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(suite.T(), body.LoadBalancers, 2)
+	assert.Less(suite.T(), body.LoadBalancers[0].ID, body.LoadBalancers[1].ID)
+}
+
+func (suite *srvTestSuite) TestAdminAuth() { //nolint:govet
+	e, err := echox.NewServer(zap.NewNop(), echox.Config{}, nil)
+	require.NoError(suite.T(), err, "unexpected error creating new server")
+
+	key := []byte("admin-test-signing-key")
+
+	auth, err := echojwtx.NewAuth(context.TODO(), echojwtx.AuthConfig{Issuer: "https://issuer.example.com", Audience: "lb-operator"},
+		echojwtx.WithJWTConfig(echojwt.Config{
+			KeyFunc: func(*jwt.Token) (interface{}, error) { return key, nil },
+		}),
+	)
+	require.NoError(suite.T(), err)
+
+	s := &Server{
+		Echo:      e,
+		AdminAuth: auth,
+	}
+
+	s.Echo.AddHandler(s)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:   "https://issuer.example.com",
+		Audience: jwt.ClaimStrings{"lb-operator"},
+		Subject:  "idntusr-admin",
+	}).SignedString(key)
+	require.NoError(suite.T(), err)
+
+	type testCase struct {
+		name   string
+		token  string
+		status int
+	}
+
+	testCases := []testCase{
+		{name: "no token", status: http.StatusUnauthorized},
+		{name: "invalid token", token: "invalid", status: http.StatusUnauthorized},
+		{name: "valid token", token: token, status: http.StatusOK},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/loadbalancers", nil)
+			if tcase.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tcase.token)
+			}
+
+			rec := httptest.NewRecorder()
+			s.Echo.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tcase.status, rec.Code)
+		})
+	}
+
+	// the version endpoint stays public
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	rec := httptest.NewRecorder()
+	s.Echo.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+}
+
+func (suite *srvTestSuite) TestPauseLoadBalancerHandlers() { //nolint:govet
+	e, err := echox.NewServer(zap.NewNop(), echox.Config{}, nil)
+	require.NoError(suite.T(), err, "unexpected error creating new server")
+
+	// the load-balancer-api is unavailable, pausing does not depend on it
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer api.Close()
+
+	id := gidx.MustNewID(LBPrefix)
+
+	s := &Server{
+		Echo:          e,
+		Logger:        zap.NewNop().Sugar(),
+		APIClient:     lbapi.NewClient(api.URL),
+		LoadBalancers: make(map[string]*runner),
+		AdminInsecure: true,
+	}
+
+	s.Echo.AddHandler(s)
+
+	type testCase struct {
+		name     string
+		path     string
+		status   int
+		contains string
+	}
+
+	testCases := []testCase{
+		{name: "invalid id", path: "/admin/loadbalancers/not-an-id/pause", status: http.StatusBadRequest},
+		{name: "invalid mode", path: "/admin/loadbalancers/" + id.String() + "/pause?mode=sometimes", status: http.StatusBadRequest},
+		{name: "resume unknown", path: "/admin/loadbalancers/" + id.String() + "/resume", status: http.StatusNotFound},
+		{name: "pause", path: "/admin/loadbalancers/" + id.String() + "/pause?mode=skip", status: http.StatusOK, contains: `"paused":"skip"`},
+		{name: "reconcile paused", path: "/admin/loadbalancers/" + id.String() + "/reconcile", status: http.StatusConflict},
+		{name: "resume", path: "/admin/loadbalancers/" + id.String() + "/resume", status: http.StatusOK},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tcase.path, nil)
+			rec := httptest.NewRecorder()
+			s.Echo.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tcase.status, rec.Code, rec.Body.String())
+			assert.Contains(t, rec.Body.String(), tcase.contains)
+		})
+	}
+
+	r, ok := s.runners()[id.String()]
+	require.True(suite.T(), ok)
+	assert.Equal(suite.T(), pauseNone, r.pauseMode())
+
+	r.stop()
+}
+
+func (suite *srvTestSuite) TestPauseLoadBalancerLocation() { //nolint:govet
+	e, err := echox.NewServer(zap.NewNop(), echox.Config{}, nil)
+	require.NoError(suite.T(), err, "unexpected error creating new server")
+
+	managed := gidx.MustNewID(LBPrefix)
+	other := gidx.MustNewID(LBPrefix)
+
+	fixtures, err := LoadFixtures(strings.NewReader(fmt.Sprintf(`[
+		{"id": %q, "name": "managed", "location": {"id": "lctnloc-managed"}},
+		{"id": %q, "name": "other", "location": {"id": "lctnloc-other"}}
+	]`, managed, other)))
+	require.NoError(suite.T(), err)
+
+	s := &Server{
+		Echo:          e,
+		Context:       context.TODO(),
+		Logger:        zap.NewNop().Sugar(),
+		APIClient:     NewFixtureClient(fixtures),
+		LoadBalancers: make(map[string]*runner),
+		Locations:     []string{"managed"},
+		AdminInsecure: true,
+	}
+
+	s.Echo.AddHandler(s)
+
+	type testCase struct {
+		name   string
+		id     gidx.PrefixedID
+		status int
+	}
+
+	testCases := []testCase{
+		{name: "other location", id: other, status: http.StatusNotFound},
+		{name: "unknown loadbalancer", id: gidx.MustNewID(LBPrefix), status: http.StatusBadGateway},
+		{name: "managed location", id: managed, status: http.StatusOK},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/loadbalancers/"+tcase.id.String()+"/pause", nil)
+			rec := httptest.NewRecorder()
+			s.Echo.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tcase.status, rec.Code, rec.Body.String())
+		})
+	}
+
+	assert.NotContains(suite.T(), s.runners(), other.String())
+
+	for _, r := range s.runners() {
+		r.stop()
+	}
+}
+
+func (suite *srvTestSuite) TestAdminRoutesWithoutAuth() { //nolint:govet
+	id := gidx.MustNewID(LBPrefix)
+
+	type testCase struct {
+		name     string
+		insecure bool
+		status   int
+	}
+
+	testCases := []testCase{
+		{name: "admin api disabled", status: http.StatusNotFound},
+		{name: "insecure", insecure: true, status: http.StatusOK},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			e, err := echox.NewServer(zap.NewNop(), echox.Config{}, nil)
+			require.NoError(t, err, "unexpected error creating new server")
+
+			logger, levels := NewLeveledLogger(zap.NewNop().Sugar(), false)
+
+			s := &Server{
+				Echo:          e,
+				Logger:        logger,
+				LogLevels:     levels,
+				LoadBalancers: make(map[string]*runner),
+				AdminInsecure: tcase.insecure,
+				inventory:     newLBInventory(),
+			}
+
+			s.Echo.AddHandler(s)
+
+			for _, route := range []struct{ method, path string }{
+				{http.MethodGet, "/admin/loadbalancers"},
+				{http.MethodGet, "/admin/loglevel"},
+				{http.MethodPut, "/admin/loglevel/loadbalancers/" + id.String()},
+			} {
+				req := httptest.NewRequest(route.method, route.path, nil)
+				rec := httptest.NewRecorder()
+				s.Echo.Handler().ServeHTTP(rec, req)
+
+				assert.Equal(t, tcase.status, rec.Code, "%s %s", route.method, route.path)
+			}
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestLogLevelHandlers() { //nolint:govet
+	e, err := echox.NewServer(zap.NewNop(), echox.Config{}, nil)
+	require.NoError(suite.T(), err, "unexpected error creating new server")
+
+	logger, levels := NewLeveledLogger(zap.NewNop().Sugar(), false)
+
+	id := gidx.MustNewID(LBPrefix)
+	location := gidx.MustNewID("lctnloc")
+
+	s := &Server{
+		Echo:          e,
+		Logger:        logger,
+		LogLevels:     levels,
+		AdminInsecure: true,
+	}
+
+	s.Echo.AddHandler(s)
+
+	type testCase struct {
+		name     string
+		method   string
+		path     string
+		status   int
+		contains string
+	}
+
+	testCases := []testCase{
+		{name: "invalid loadbalancer", method: http.MethodPut, path: "/admin/loglevel/loadbalancers/not-an-id", status: http.StatusBadRequest},
+		{name: "invalid location", method: http.MethodPut, path: "/admin/loglevel/locations/not-an-id", status: http.StatusBadRequest},
+		{name: "debug loadbalancer", method: http.MethodPut, path: "/admin/loglevel/loadbalancers/" + id.String(), status: http.StatusOK, contains: `"loadBalancers":["` + id.String() + `"]`},
+		{name: "debug location", method: http.MethodPut, path: "/admin/loglevel/locations/" + location.String(), status: http.StatusOK, contains: `"locations":["` + location.String() + `"]`},
+		{name: "reset loadbalancer", method: http.MethodDelete, path: "/admin/loglevel/loadbalancers/" + id.String(), status: http.StatusOK, contains: `"loadBalancers":[]`},
+		{name: "current", method: http.MethodGet, path: "/admin/loglevel", status: http.StatusOK, contains: `"level":"info"`},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			req := httptest.NewRequest(tcase.method, tcase.path, nil)
+			rec := httptest.NewRecorder()
+			s.Echo.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tcase.status, rec.Code, rec.Body.String())
+			assert.Contains(t, rec.Body.String(), tcase.contains)
+		})
+	}
+
+	assert.False(suite.T(), levels.debugEnabled(id.String(), ""))
+	assert.True(suite.T(), levels.debugEnabled(gidx.MustNewID(LBPrefix).String(), location.String()))
+}
@@ -2,17 +2,19 @@ package srv
 
 import (
 	"context"
-	"encoding/hex"
 	"errors"
-	"fmt"
 
 	"golang.org/x/exp/slices"
 
-	"github.com/lestrrat-go/backoff/v2"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	applyv1 "k8s.io/client-go/applyconfigurations/core/v1"
 	applymetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
@@ -25,36 +27,55 @@ const (
 	kubeNSLength      = 63
 )
 
-func (s *Server) removeNamespace(ctx context.Context, ns string) error {
-	s.Logger.Debugw("removing namespace", "namespace", ns)
+func (s *Server) removeNamespace(ctx context.Context, ns, lbID string) error {
+	s.logger(ctx).Debugw("removing namespace", "namespace", ns)
 
-	kc, err := kubernetes.NewForConfig(s.KubeClient)
+	kc, err := kubernetes.NewForConfig(s.restConfig(ctx))
 	if err != nil {
-		s.Logger.Debugw("unable to authenticate against kubernetes cluster", "error", err)
+		s.logger(ctx).Debugw("unable to authenticate against kubernetes cluster", "error", err)
 		return err
 	}
 
-	err = kc.CoreV1().Namespaces().Delete(ctx, ns, metav1.DeleteOptions{})
-	if err != nil {
+	err = s.retry(ctx, OpNamespaceDelete, func(ctx context.Context) error {
+		return s.withPhaseTimeout(ctx, phaseNamespace, func(ctx context.Context) error {
+			err := withSpan(ctx, "kubernetes.namespace.delete", func(ctx context.Context) error {
+				return kc.CoreV1().Namespaces().Delete(ctx, ns, metav1.DeleteOptions{})
+			}, attribute.String("kubernetes.namespace", ns))
+			s.audit(ctx, auditRecord{Action: auditNamespaceDelete, LoadBalancerID: lbID, Namespace: ns}, err)
+
+			return err
+		})
+	})
+
+	switch {
+	case apierrors.IsNotFound(err):
+		s.logger(ctx).Debugw("namespace already removed", "namespace", ns)
+		s.stuckNamespaces.remove(ns)
+
+		return nil
+	case err != nil:
 		return err
 	}
 
-	return nil
+	return s.waitForNamespaceTermination(ctx, kc, ns)
 }
 
 // CreateNamespace creates namespaces for the specified group that is
-// provided in the event received
-func (s *Server) CreateNamespace(ctx context.Context, hash string) (*v1.Namespace, error) {
-	s.Logger.Debugw("ensuring namespace exists", "namespace", hash)
+// provided in the event received. When a loadbalancer is provided its full ID
+// is recorded as an annotation on the namespace. Labels and annotations are
+// server-side applied so that configuration changes are reconciled on existing
+// namespaces, including the removal of labels no longer configured.
+func (s *Server) CreateNamespace(ctx context.Context, hash string, lb *loadBalancer) (*v1.Namespace, error) {
+	s.logger(ctx).Debugw("ensuring namespace exists", "namespace", hash)
 
 	if !checkNameLength(hash, kubeNSLength) {
-		s.Logger.Debugw("namespace name is empty or too long", "namespace", hash, "limit", kubeNSLength)
+		s.logger(ctx).Debugw("namespace name is empty or too long", "namespace", hash, "limit", kubeNSLength)
 		return nil, errInvalidObjectNameLength
 	}
 
-	kc, err := kubernetes.NewForConfig(s.KubeClient)
+	kc, err := kubernetes.NewForConfig(s.restConfig(ctx))
 	if err != nil {
-		s.Logger.Debugw("unable to authenticate against kubernetes cluster", "error", err)
+		s.logger(ctx).Debugw("unable to authenticate against kubernetes cluster", "error", err)
 		return nil, err
 	}
 
@@ -64,25 +85,72 @@ func (s *Server) CreateNamespace(ctx context.Context, hash string) (*v1.Namespac
 			APIVersion: strPt("v1"),
 		},
 		ObjectMetaApplyConfiguration: &applymetav1.ObjectMetaApplyConfiguration{
-			Name: &hash,
-			Labels: map[string]string{
-				"com.infratographer.lb-operator/managed": "true",
-				"com.infratographer.lb-operator/lb-id":   hash,
-			},
+			Name:        &hash,
+			Labels:      s.namespaceLabels(hash, lb),
+			Annotations: s.namespaceAnnotations(lb),
 		},
 		Spec:   &applyv1.NamespaceSpecApplyConfiguration{},
 		Status: &applyv1.NamespaceStatusApplyConfiguration{},
 	}
 
-	ns, err := kc.CoreV1().Namespaces().Apply(ctx, &apSpec, metav1.ApplyOptions{FieldManager: "loadbalanceroperator"})
-	if err != nil {
-		s.Logger.Debugw("unable to create namespace", "error", err, "namespace", hash)
-		return nil, errors.Join(err, errInvalidNamespace)
+	var ns *v1.Namespace
+
+	lbID := ""
+	if lb != nil {
+		lbID = lb.loadBalancerID.String()
 	}
 
-	if err := attachRoleBinding(ctx, kc, hash); err != nil {
-		s.Logger.Debugw("unable to attach namespace manager rolebinding to namespace", "error", err)
-		return nil, errors.Join(err, errInvalidRoleBinding)
+	err = s.withPhaseTimeout(ctx, phaseNamespace, func(ctx context.Context) error {
+		nsAttr := attribute.String("kubernetes.namespace", hash)
+
+		err := withSpan(ctx, "kubernetes.namespace.apply", func(ctx context.Context) error {
+			var err error
+
+			ns, err = kc.CoreV1().Namespaces().Apply(ctx, &apSpec, metav1.ApplyOptions{FieldManager: "loadbalanceroperator"})
+
+			return err
+		}, nsAttr)
+		s.audit(ctx, auditRecord{
+			Action:          auditNamespaceApply,
+			LoadBalancerID:  lbID,
+			Namespace:       hash,
+			ValuesHashAfter: valuesHash(apSpec.ObjectMetaApplyConfiguration),
+		}, err)
+
+		if err != nil {
+			s.logger(ctx).Debugw("unable to create namespace", "error", err, "namespace", hash)
+			return errors.Join(err, errInvalidNamespace)
+		}
+
+		err = withSpan(ctx, "kubernetes.rolebinding.apply", func(ctx context.Context) error {
+			return attachRoleBinding(ctx, kc, hash)
+		}, nsAttr)
+		s.audit(ctx, auditRecord{Action: auditRoleBindingApply, LoadBalancerID: lbID, Namespace: hash}, err)
+
+		if err != nil {
+			s.logger(ctx).Debugw("unable to attach namespace manager rolebinding to namespace", "error", err)
+			return errors.Join(err, errInvalidRoleBinding)
+		}
+
+		// guardrails are only applied to namespaces that belong to a loadbalancer
+		if lb == nil {
+			return nil
+		}
+
+		err = withSpan(ctx, "kubernetes.guardrails.apply", func(ctx context.Context) error {
+			return s.applyGuardrails(ctx, kc, hash, lb)
+		}, nsAttr)
+		s.audit(ctx, auditRecord{Action: auditGuardrailsApply, LoadBalancerID: lbID, Namespace: hash}, err)
+
+		if err != nil {
+			s.logger(ctx).Debugw("unable to apply namespace guardrails", "error", err, "namespace", hash)
+			return errors.Join(err, errInvalidGuardrails)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return ns, nil
@@ -117,123 +185,219 @@ func attachRoleBinding(ctx context.Context, client *kubernetes.Clientset, namesp
 
 // newDeployment deploys a loadBalancer based upon the configuration provided
 // from the event that is processed.
-func (s *Server) newDeployment(ctx context.Context, lb *loadBalancer) error {
-	ctx, span := otel.Tracer(instrumentationName).Start(ctx, "newDeployment")
+func (s *Server) newDeployment(ctx context.Context, lb *loadBalancer) (err error) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, "newDeployment", trace.WithAttributes(attribute.String("loadbalancer.id", lb.loadBalancerID.String())))
 	defer span.End()
+	defer func() { recordSpanError(span, err) }()
 
-	hash := hashLBName(lb.loadBalancerID.String())
-
-	if _, err := s.CreateNamespace(ctx, hash); err != nil {
-		s.Logger.Debugw("unable to create namespace", "error", err, "namespace", hash, "loadBalancer", lb.loadBalancerID.String())
+	names, err := s.lbNames(ctx, lb)
+	if err != nil {
+		s.logger(ctx).Debugw("unable to resolve loadbalancer names", "error", err, "loadBalancer", lb.loadBalancerID.String())
 		return err
 	}
 
-	releaseName := fmt.Sprintf("lb-%s", hash)
-	if !checkNameLength(releaseName, helmReleaseLength) {
-		releaseName = releaseName[0:helmReleaseLength]
+	hash, releaseName := names.namespace, names.release
+
+	if _, err := s.CreateNamespace(ctx, hash, lb); err != nil {
+		s.logger(ctx).Debugw("unable to create namespace", "error", err, "namespace", hash, "loadBalancer", lb.loadBalancerID.String())
+		return err
 	}
 
 	values, err := s.newHelmValues(lb)
 	if err != nil {
-		s.Logger.Debugw("unable to prepare chart values", "error", err, "loadBalancer", lb.loadBalancerID.String(), "namespace", hash)
+		s.logger(ctx).Debugw("unable to prepare chart values", "error", err, "loadBalancer", lb.loadBalancerID.String(), "namespace", hash)
 		return err
 	}
 
-	client, err := s.newHelmClient(hash)
+	client, err := s.newHelmClient(ctx, hash)
 	if err != nil {
-		s.Logger.Debugw("unable to initialize helm client", "err", err, "loadBalancer", lb.loadBalancerID.String(), "namespace", hash)
+		s.logger(ctx).Debugw("unable to initialize helm client", "err", err, "loadBalancer", lb.loadBalancerID.String(), "namespace", hash)
 		return err
 	}
 
 	hc := action.NewInstall(client)
 	hc.ReleaseName = releaseName
 	hc.Namespace = hash
-	_, err = hc.Run(s.Chart, values)
+	err = s.retry(ctx, OpInstall, func(ctx context.Context) error {
+		return s.withPhaseTimeout(ctx, phaseHelm, func(ctx context.Context) error {
+			var rel *release.Release
+
+			err := runHelmAction(ctx, OpInstall, hash, releaseName, func(ctx context.Context) error {
+				var err error
+
+				rel, err = hc.RunWithContext(ctx, s.Chart, values)
+
+				return err
+			})
+			s.audit(ctx, auditRecord{
+				Action:          auditHelmInstall,
+				LoadBalancerID:  lb.loadBalancerID.String(),
+				Namespace:       hash,
+				Release:         releaseName,
+				ValuesHashAfter: helmValuesHash(values),
+			}, err)
+
+			if err == nil {
+				lb.revision = rel.Version
+			}
+
+			return err
+		})
+	})
 
 	switch err {
 	case nil:
-		s.Logger.Infow("loadbalancer deployed successfully", "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+		s.logger(ctx).Infow("loadbalancer deployed successfully", "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+		s.recordEvent(ctx, lb, v1.EventTypeNormal, eventReasonInstalled, "installed release %s", releaseName)
 	case driver.ErrReleaseExists:
-		s.Logger.Debugw("loadbalancer already exists, proceeding to upgrade...", "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+		s.logger(ctx).Debugw("loadbalancer already exists, proceeding to upgrade...", "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
 	default:
-		s.Logger.Debugw("unable to deploy loadbalancer", "error", err, "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+		s.logger(ctx).Debugw("unable to deploy loadbalancer", "error", err, "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+		s.recordEvent(ctx, lb, v1.EventTypeWarning, eventReasonInstallFailed, "unable to install release %s: %s", releaseName, err)
+
 		return err
 	}
 
 	return nil
 }
 
-func (s *Server) updateDeployment(ctx context.Context, lb *loadBalancer) error {
-	_, span := otel.Tracer(instrumentationName).Start(ctx, "updateDeployment")
+func (s *Server) updateDeployment(ctx context.Context, lb *loadBalancer) (err error) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, "updateDeployment", trace.WithAttributes(attribute.String("loadbalancer.id", lb.loadBalancerID.String())))
 	defer span.End()
+	defer func() { recordSpanError(span, err) }()
 
-	hash := hashLBName(lb.loadBalancerID.String())
-
-	releaseName := fmt.Sprintf("lb-%s", hash)
-	if !checkNameLength(releaseName, helmReleaseLength) {
-		releaseName = releaseName[0:helmReleaseLength]
+	names, err := s.lbNames(ctx, lb)
+	if err != nil {
+		s.logger(ctx).Debugw("unable to resolve loadbalancer names", "error", err, "loadBalancer", lb.loadBalancerID.String())
+		return err
 	}
 
+	hash, releaseName := names.namespace, names.release
+
 	values, err := s.newHelmValues(lb)
 	if err != nil {
-		s.Logger.Debugw("unable to prepare chart values", "error", err, "loadBalancer", lb.loadBalancerID.String())
+		s.logger(ctx).Debugw("unable to prepare chart values", "error", err, "loadBalancer", lb.loadBalancerID.String())
 		return err
 	}
 
-	client, err := s.newHelmClient(hash)
+	client, err := s.newHelmClient(ctx, hash)
 	if err != nil {
-		s.Logger.Debugw("unable to initialize helm client", "error", err, "loadBalancer", lb.loadBalancerID.String())
+		s.logger(ctx).Debugw("unable to initialize helm client", "error", err, "loadBalancer", lb.loadBalancerID.String())
 		return err
 	}
 
+	previous := s.releaseValuesHash(client, releaseName)
+
 	hc := action.NewUpgrade(client)
 	hc.Namespace = hash
-	_, err = hc.Run(releaseName, s.Chart, values)
+	err = s.withPhaseTimeout(ctx, phaseHelm, func(ctx context.Context) error {
+		var rel *release.Release
+
+		err := runHelmAction(ctx, OpUpgrade, hash, releaseName, func(ctx context.Context) error {
+			var err error
+
+			rel, err = hc.RunWithContext(ctx, releaseName, s.Chart, values)
+
+			return err
+		})
+		s.audit(ctx, auditRecord{
+			Action:           auditHelmUpgrade,
+			LoadBalancerID:   lb.loadBalancerID.String(),
+			Namespace:        hash,
+			Release:          releaseName,
+			ValuesHashBefore: previous,
+			ValuesHashAfter:  helmValuesHash(values),
+		}, err)
+
+		if err == nil {
+			lb.revision = rel.Version
+		}
+
+		return err
+	})
 
 	if err != nil {
-		s.Logger.Debugw("unable to upgrade loadbalancer", "error", err, "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+		s.logger(ctx).Debugw("unable to upgrade loadbalancer", "error", err, "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+		s.recordEvent(ctx, lb, v1.EventTypeWarning, eventReasonUpgradeFailed, "unable to upgrade release %s: %s", releaseName, err)
+
 		return err
 	}
 
-	s.Logger.Infow("loadbalancer upgraded successfully", "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+	s.logger(ctx).Infow("loadbalancer upgraded successfully", "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+	s.recordEvent(ctx, lb, v1.EventTypeNormal, eventReasonUpgraded, "upgraded release %s", releaseName)
 
 	return nil
 }
 
-func (s *Server) removeDeployment(ctx context.Context, lb *loadBalancer) error {
-	ctx, span := otel.Tracer(instrumentationName).Start(ctx, "removeDeployment")
+func (s *Server) removeDeployment(ctx context.Context, lb *loadBalancer) (err error) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, "removeDeployment", trace.WithAttributes(attribute.String("loadbalancer.id", lb.loadBalancerID.String())))
 	defer span.End()
+	defer func() { recordSpanError(span, err) }()
 
-	hash := hashLBName(lb.loadBalancerID.String())
-
-	releaseName := fmt.Sprintf("lb-%s", hash)
-	if !checkNameLength(releaseName, helmReleaseLength) {
-		releaseName = releaseName[0:helmReleaseLength]
+	names, err := s.lbNames(ctx, lb)
+	if err != nil {
+		s.logger(ctx).Debugw("unable to resolve loadbalancer names", "error", err, "loadBalancer", lb.loadBalancerID.String())
+		return err
 	}
 
-	client, err := s.newHelmClient(hash)
+	hash, releaseName := names.namespace, names.release
+
+	client, err := s.newHelmClient(ctx, hash)
 	if err != nil {
-		s.Logger.Debugw("unable to initialize helm client", "error", err, "loadBalancer", lb.loadBalancerID.String(), "namespace", hash, "releaseName", releaseName)
+		s.logger(ctx).Debugw("unable to initialize helm client", "error", err, "loadBalancer", lb.loadBalancerID.String(), "namespace", hash, "releaseName", releaseName)
 		return err
 	}
 
 	hc := action.NewUninstall(client)
-	_, err = hc.Run(releaseName)
+	err = s.retry(ctx, OpUninstall, func(ctx context.Context) error {
+		var resp *release.UninstallReleaseResponse
+
+		// uninstall does not take a context, the span still times the action
+		err := runHelmAction(ctx, OpUninstall, hash, releaseName, func(context.Context) error {
+			var err error
+
+			resp, err = hc.Run(releaseName)
+
+			return err
+		})
+
+		rec := auditRecord{
+			Action:         auditHelmUninstall,
+			LoadBalancerID: lb.loadBalancerID.String(),
+			Namespace:      hash,
+			Release:        releaseName,
+		}
+
+		if resp != nil && resp.Release != nil {
+			rec.ValuesHashBefore = helmValuesHash(resp.Release.Config)
+		}
+
+		s.audit(ctx, rec, err)
 
-	if err != nil {
-		s.Logger.Debugw("unable to remove loadBalancer", "error", err, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String(), "namespace", hash)
 		return err
-	}
+	})
 
-	s.Logger.Infow("loadbalancer removed successfully", "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+	switch {
+	case errors.Is(err, driver.ErrReleaseNotFound):
+		// a previous attempt may have removed the release but not finished removing the namespace
+		s.logger(ctx).Debugw("loadbalancer release already removed", "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String(), "namespace", hash)
+	case err != nil:
+		s.logger(ctx).Debugw("unable to remove loadBalancer", "error", err, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String(), "namespace", hash)
+		s.recordEvent(ctx, lb, v1.EventTypeWarning, eventReasonUninstallFailed, "unable to uninstall release %s: %s", releaseName, err)
 
-	err = s.removeNamespace(ctx, hash)
+		return err
+	default:
+		s.logger(ctx).Infow("loadbalancer removed successfully", "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+		s.recordEvent(ctx, lb, v1.EventTypeNormal, eventReasonUninstalled, "uninstalled release %s", releaseName)
+	}
+
+	err = s.removeNamespace(ctx, hash, lb.loadBalancerID.String())
 	if err != nil {
-		s.Logger.Debugw("unable to remove namespace", "error", err, "namespace", hash, "loadBalancer", lb.loadBalancerID.String())
+		s.logger(ctx).Debugw("unable to remove namespace", "error", err, "namespace", hash, "loadBalancer", lb.loadBalancerID.String())
 		return err
 	}
 
-	s.Logger.Infow("namespace removed successfully", "namespace", hash, "loadBalancer", lb.loadBalancerID.String())
+	s.logger(ctx).Infow("namespace removed successfully", "namespace", hash, "loadBalancer", lb.loadBalancerID.String())
 
 	return nil
 }
@@ -246,33 +410,35 @@ func checkNameLength(name string, limit int) bool {
 	return len(name) <= limit && len(name) > 0
 }
 
-func hashLBName(name string) string {
-	return hex.EncodeToString([]byte(name))
-}
-
 func (s *Server) createDeployment(ctx context.Context, lb *loadBalancer) error {
 	if !slices.Contains(s.Locations, lb.lbData.Location.ID) {
-		s.Logger.Warn("load-balancer location not found in operator watch locations, skipping...", "location", lb.lbData.Location.ID, "loadBalancer", lb.loadBalancerID)
+		s.logger(ctx).Warn("load-balancer location not found in operator watch locations, skipping...", "location", lb.lbData.Location.ID, "loadBalancer", lb.loadBalancerID)
 		return nil
 	}
 
-	hash := hashLBName(lb.loadBalancerID.String())
-
-	releaseName := fmt.Sprintf("lb-%s", hash)
-	if !checkNameLength(releaseName, helmReleaseLength) {
-		releaseName = releaseName[0:helmReleaseLength]
+	names, err := s.lbNames(ctx, lb)
+	if err != nil {
+		s.logger(ctx).Debugw("unable to resolve loadbalancer names", "error", err, "loadBalancer", lb.loadBalancerID.String())
+		return err
 	}
 
-	client, err := s.newHelmClient(hash)
+	hash, releaseName := names.namespace, names.release
+
+	client, err := s.newHelmClient(ctx, hash)
 	if err != nil {
-		s.Logger.Debugw("unable to initialize helm client", "error", err, "loadBalancer", lb.loadBalancerID.String())
+		s.logger(ctx).Debugw("unable to initialize helm client", "error", err, "loadBalancer", lb.loadBalancerID.String())
 		return err
 	}
 
 	histClient := action.NewHistory(client)
 	histClient.Max = 1
 
-	if _, err := histClient.Run(releaseName); errors.Is(err, driver.ErrReleaseNotFound) {
+	err = withSpan(ctx, "helm.history", func(context.Context) error {
+		_, err := histClient.Run(releaseName)
+		return err
+	}, attribute.String("helm.namespace", hash), attribute.String("helm.release", releaseName))
+
+	if errors.Is(err, driver.ErrReleaseNotFound) {
 		err = s.newDeployment(ctx, lb)
 		if err != nil && !errors.Is(err, driver.ErrReleaseExists) {
 			return err
@@ -283,17 +449,125 @@ func (s *Server) createDeployment(ctx context.Context, lb *loadBalancer) error {
 		}
 	}
 
-	b := s.BackoffConfig.Start(ctx)
-	for backoff.Continue(b) {
-		err = s.updateDeployment(ctx, lb)
-		if err == nil {
-			return nil
-		} else {
-			s.Logger.Debugw("unable to update loadbalancer, retrying...", "error", err, "loadBalancer", lb.loadBalancerID.String())
+	// re-apply the namespace so label and annotation changes reach existing loadbalancers
+	if _, err := s.CreateNamespace(ctx, hash, lb); err != nil {
+		s.logger(ctx).Debugw("unable to reconcile namespace", "error", err, "namespace", hash, "loadBalancer", lb.loadBalancerID.String())
+		return err
+	}
+
+	err = s.retry(ctx, OpUpgrade, func(ctx context.Context) error {
+		err := s.updateDeployment(ctx, lb)
+		if err != nil {
+			s.logger(ctx).Debugw("unable to update loadbalancer", "error", err, "loadBalancer", lb.loadBalancerID.String(), "retryable", isRetryable(err))
 		}
+
+		return err
+	})
+	if err != nil {
+		s.logger(ctx).Debugw("failed to update loadbalancer", "error", err, "loadBalancer", lb.loadBalancerID.String())
+
+		if rbErr := s.rollbackDeployment(ctx, lb); rbErr != nil {
+			s.logger(ctx).Debugw("failed to roll back loadbalancer", "error", rbErr, "loadBalancer", lb.loadBalancerID.String())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// rollbackDeployment rolls the release of a loadbalancer back to its previous revision
+// when the latest revision failed to deploy, so that the last working release is kept
+func (s *Server) rollbackDeployment(ctx context.Context, lb *loadBalancer) (err error) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, "rollbackDeployment", trace.WithAttributes(attribute.String("loadbalancer.id", lb.loadBalancerID.String())))
+	defer span.End()
+	defer func() { recordSpanError(span, err) }()
+
+	names, err := s.lbNames(ctx, lb)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.newHelmClient(ctx, names.namespace)
+	if err != nil {
+		return err
 	}
 
-	s.Logger.Debugw("failed to update loadbalancer", "error", err, "loadBalancer", lb.loadBalancerID.String())
+	return s.rollbackRelease(ctx, client, lb, names.namespace, names.release)
+}
+
+// rollbackRelease rolls a release back to its newest revision that was deployed. Releases
+// whose latest revision is deployed, or that have never been deployed, are left as they are.
+func (s *Server) rollbackRelease(ctx context.Context, client *action.Configuration, lb *loadBalancer, hash, releaseName string) error {
+	history, err := action.NewHistory(client).Run(releaseName)
+	if err != nil {
+		return err
+	}
+
+	releaseutil.SortByRevision(history)
+
+	if len(history) == 0 {
+		return nil
+	}
+
+	latest := history[len(history)-1]
+	if latest.Info == nil {
+		return nil
+	}
+
+	if latest.Info.Status != release.StatusFailed && !latest.Info.Status.IsPending() {
+		return nil
+	}
+
+	previous := lastDeployedRevision(history[:len(history)-1])
+	if previous == 0 {
+		s.logger(ctx).Debugw("no deployed revision to roll back to", "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+		return nil
+	}
+
+	hc := action.NewRollback(client)
+	hc.Version = previous
+
+	// rollback does not take a context, the span still times the action
+	err = runHelmAction(ctx, OpRollback, hash, releaseName, func(context.Context) error {
+		return hc.Run(releaseName)
+	})
+	s.audit(ctx, auditRecord{
+		Action:         auditHelmRollback,
+		LoadBalancerID: lb.loadBalancerID.String(),
+		Namespace:      hash,
+		Release:        releaseName,
+	}, err)
+
+	if err != nil {
+		s.logger(ctx).Debugw("unable to roll back loadbalancer", "error", err, "namespace", hash, "releaseName", releaseName, "loadBalancer", lb.loadBalancerID.String())
+		s.recordEvent(ctx, lb, v1.EventTypeWarning, eventReasonRollbackFailed, "unable to roll back release %s to revision %d: %s", releaseName, previous, err)
+
+		return err
+	}
+
+	// a rollback is recorded as a new revision
+	lb.revision = latest.Version + 1
+
+	s.logger(ctx).Infow("loadbalancer rolled back", "namespace", hash, "releaseName", releaseName, "revision", previous, "loadBalancer", lb.loadBalancerID.String())
+	s.recordEvent(ctx, lb, v1.EventTypeNormal, eventReasonRolledBack, "rolled back release %s to revision %d", releaseName, previous)
+
+	return nil
+}
+
+// lastDeployedRevision returns the newest revision of a history sorted by revision that
+// was deployed, or 0 when none of them were
+func lastDeployedRevision(history []*release.Release) int {
+	for i := len(history) - 1; i >= 0; i-- {
+		rel := history[i]
+		if rel.Info == nil {
+			continue
+		}
+
+		if rel.Info.Status == release.StatusDeployed || rel.Info.Status == release.StatusSuperseded {
+			return rel.Version
+		}
+	}
 
-	return err
+	return 0
 }
@@ -3,6 +3,7 @@ package srv
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/gobuffalo/packr/v2/file/resolver/encoding/hex"
@@ -26,12 +27,26 @@ var dummyLBID = "loadbal-lkjasdlfkjasdf"
 func (suite *srvTestSuite) TestHashLBName() {
 	hash := hashLBName(dummyLBID)
 
-	assert.NotNil(suite.T(), hash)
-	assert.IsType(suite.T(), "", hash)
+	assert.Equal(suite.T(), hash, hashLBName(dummyLBID))
+	assert.NotEqual(suite.T(), hash, hashLBName(dummyLBID+"x"))
+	assert.True(suite.T(), strings.HasPrefix(hash, "lb-"))
+	assert.True(suite.T(), checkNameLength(hash, helmReleaseLength))
+
+	long := hashLBName("loadbal-reallyreallyreallyreallyreallyreallylongreallylong")
+	assert.Len(suite.T(), long, len(hash))
+}
+
+func (suite *srvTestSuite) TestLegacyHashLBName() {
+	hash := legacyHashLBName(dummyLBID)
 
 	dec, _ := hex.DecodeString(hash)
 
 	assert.Equal(suite.T(), dummyLBID, string(dec))
+
+	names := legacyLBNames(dummyLBID)
+	assert.Equal(suite.T(), hash, names.namespace)
+	assert.Equal(suite.T(), "lb-"+hash, names.release)
+	assert.Equal(suite.T(), namingSchemeLegacy, names.scheme)
 }
 
 func (suite *srvTestSuite) TestCheckNameLength() {
@@ -153,7 +168,7 @@ func (suite *srvTestSuite) TestCreateNamespace() {
 			assert.Nil(t, err)
 
 			hash := hashLBName(lb.loadBalancerID.String())
-			ns, err := srv.CreateNamespace(context.TODO(), hash)
+			ns, err := srv.CreateNamespace(context.TODO(), hash, lb)
 
 			if tcase.expectError {
 				assert.NotNil(t, err)
@@ -162,6 +177,7 @@ func (suite *srvTestSuite) TestCreateNamespace() {
 				assert.Nil(t, err)
 				assert.Contains(t, ns.Labels, "com.infratographer.lb-operator/managed")
 				assert.Contains(t, ns.Labels, "com.infratographer.lb-operator/lb-id")
+				assert.Equal(t, lb.loadBalancerID.String(), ns.Annotations["com.infratographer.lb-operator/lb-id"])
 			}
 		})
 	}
@@ -239,11 +255,11 @@ func (suite *srvTestSuite) TestRemoveNamespace() {
 
 			// TODO: check that namespace doesn't exist
 
-			_, _ = srv.CreateNamespace(context.TODO(), hash)
+			_, _ = srv.CreateNamespace(context.TODO(), hash, lb)
 
 			// TODO: check that namespace does exist
 
-			err = srv.removeNamespace(context.TODO(), hash)
+			err = srv.removeNamespace(context.TODO(), hash, "")
 
 			// TODO: check that namespace does not exist
 
@@ -353,7 +369,7 @@ func (suite *srvTestSuite) TestNewDeployment() {
 
 			// TODO: check that namespace doesn't exist
 
-			_, _ = srv.CreateNamespace(context.TODO(), hash)
+			_, _ = srv.CreateNamespace(context.TODO(), hash, lb)
 
 			// TODO: check that namespace does exist
 
@@ -439,7 +455,7 @@ func (suite *srvTestSuite) TestRemoveDeployment() {
 
 			// TODO: check that namespace does not exist
 
-			_, _ = srv.CreateNamespace(context.TODO(), hash)
+			_, _ = srv.CreateNamespace(context.TODO(), hash, lb)
 
 			// TODO: check that namespace does exist
 			// TODO: check that release does not exist
@@ -498,7 +514,7 @@ func (suite *srvTestSuite) TestAttachRoleBinding() {
 			}
 
 			if !tcase.expectErr {
-				_, err = srv.CreateNamespace(srv.Context, tcase.namespace)
+				_, err = srv.CreateNamespace(srv.Context, tcase.namespace, nil)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -0,0 +1,214 @@
+package srv
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.infratographer.com/x/events"
+	"go.opentelemetry.io/otel/trace"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+// auditAction is a mutation made by the operator
+type auditAction string
+
+const (
+	auditNamespaceApply   auditAction = "namespace.apply"
+	auditNamespaceDelete  auditAction = "namespace.delete"
+	auditRoleBindingApply auditAction = "rolebinding.apply"
+	auditGuardrailsApply  auditAction = "guardrails.apply"
+	auditHelmInstall      auditAction = "helm.install"
+	auditHelmUpgrade      auditAction = "helm.upgrade"
+	auditHelmRollback     auditAction = "helm.rollback"
+	auditHelmUninstall    auditAction = "helm.uninstall"
+	auditMetadataWrite    auditAction = "metadata.write"
+
+	auditOutcomeSuccess = "success"
+	auditOutcomeFailure = "failure"
+
+	auditFileMode = 0o600
+)
+
+// auditRecord describes a single mutation and its outcome. Values hashes identify the
+// configuration of the object before and after the mutation without recording it.
+type auditRecord struct {
+	Time             time.Time   `json:"time"`
+	Action           auditAction `json:"action"`
+	LoadBalancerID   string      `json:"loadBalancerID,omitempty"`
+	Cluster          string      `json:"cluster,omitempty"`
+	Namespace        string      `json:"namespace,omitempty"`
+	Release          string      `json:"release,omitempty"`
+	MessageID        string      `json:"messageID,omitempty"`
+	TraceID          string      `json:"traceID,omitempty"`
+	ValuesHashBefore string      `json:"valuesHashBefore,omitempty"`
+	ValuesHashAfter  string      `json:"valuesHashAfter,omitempty"`
+	Outcome          string      `json:"outcome"`
+	Error            string      `json:"error,omitempty"`
+}
+
+// AuditLog writes a JSON line for every mutation the operator makes
+type AuditLog struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewAuditLog returns an audit log writing records to w
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{out: w}
+}
+
+// NewFileAuditLog returns an audit log appending records to the file at path
+func NewFileAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, auditFileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAuditLog(f), nil
+}
+
+// NewEventsAuditLog returns an audit log publishing each record to a NATS subject. The
+// subject must be captured by a JetStream stream, records are only written once the
+// stream acknowledges them.
+func NewEventsAuditLog(conn events.Connection, subject string) (*AuditLog, error) {
+	nc, ok := conn.Source().(*nats.Conn)
+	if !ok {
+		return nil, errAuditUnsupported
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, errors.Join(err, errAuditUnsupported)
+	}
+
+	return NewAuditLog(&natsAuditWriter{js: js, subject: subject}), nil
+}
+
+// Close closes the destination of the audit log when it can be closed
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	if c, ok := a.out.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+func (a *AuditLog) write(rec auditRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, err = a.out.Write(append(b, '\n'))
+
+	return err
+}
+
+// natsAuditWriter publishes every write as a message on the subject and waits for the
+// stream to acknowledge it
+type natsAuditWriter struct {
+	js      nats.JetStreamContext
+	subject string
+}
+
+func (w *natsAuditWriter) Write(p []byte) (int, error) {
+	if _, err := w.js.Publish(w.subject, p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// audit records the outcome of a mutation with the message and trace that caused it. Records
+// that cannot be written are logged and counted, they never fail the mutation.
+func (s *Server) audit(ctx context.Context, rec auditRecord, err error) {
+	if s.Audit == nil {
+		return
+	}
+
+	rec.Time = time.Now().UTC()
+	rec.Cluster = clusterName(ctx)
+	rec.MessageID = messageIDFromContext(ctx)
+	rec.Outcome = auditOutcomeSuccess
+
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		rec.TraceID = sc.TraceID().String()
+	}
+
+	if err != nil {
+		rec.Outcome = auditOutcomeFailure
+		rec.Error = err.Error()
+	}
+
+	if err := s.Audit.write(rec); err != nil {
+		s.Logger.Warnw("unable to write audit record", "error", err, "action", rec.Action, "loadBalancer", rec.LoadBalancerID)
+		auditWriteFailuresCounter.Inc()
+	}
+}
+
+// releaseValuesHash returns the values hash of the deployed release, or an empty string
+// when nothing is audited or the release cannot be read
+func (s *Server) releaseValuesHash(client *action.Configuration, releaseName string) string {
+	if s.Audit == nil {
+		return ""
+	}
+
+	rel, err := action.NewGet(client).Run(releaseName)
+	if err != nil {
+		return ""
+	}
+
+	return helmValuesHash(rel.Config)
+}
+
+// helmValuesHash hashes chart values. The dataplane API credentials are generated for every
+// deployment so they are left out, otherwise every upgrade would change the hash.
+func helmValuesHash(values map[string]interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	operator, _ := values["operator"].(map[string]interface{})
+	managed, _ := operator["managed"].(map[string]interface{})
+
+	if _, ok := managed[dataPlaneCredsKey]; ok {
+		managed = maps.Clone(managed)
+		delete(managed, dataPlaneCredsKey)
+
+		operator = maps.Clone(operator)
+		operator["managed"] = managed
+
+		values = maps.Clone(values)
+		values["operator"] = operator
+	}
+
+	return valuesHash(values)
+}
+
+// valuesHash returns the hex encoded sha256 of the JSON encoding of v
+func valuesHash(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
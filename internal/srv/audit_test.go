@@ -0,0 +1,107 @@
+package srv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func (suite *srvTestSuite) TestAudit() { //nolint:govet
+	type testCase struct {
+		name      string
+		messageID string
+		traceID   string
+		err       error
+		outcome   string
+	}
+
+	id := gidx.MustNewID(LBPrefix)
+
+	testCases := []testCase{
+		{
+			name:      "success",
+			messageID: "stream/42",
+			traceID:   "0102030405060708090a0b0c0d0e0f10",
+			outcome:   auditOutcomeSuccess,
+		},
+		{
+			name:    "failure",
+			err:     errors.New("release failed"), //nolint:goerr113
+			outcome: auditOutcomeFailure,
+		},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			srv := Server{Logger: zap.NewNop().Sugar(), Audit: NewAuditLog(out)}
+
+			ctx := withMessageID(context.TODO(), tcase.messageID)
+
+			if tcase.traceID != "" {
+				traceID, err := trace.TraceIDFromHex(tcase.traceID)
+				require.NoError(t, err)
+
+				ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID: traceID,
+					SpanID:  trace.SpanID{1},
+				}))
+			}
+
+			srv.audit(ctx, auditRecord{
+				Action:           auditHelmUpgrade,
+				LoadBalancerID:   id.String(),
+				Release:          "release",
+				ValuesHashBefore: "before",
+				ValuesHashAfter:  "after",
+			}, tcase.err)
+
+			rec := auditRecord{}
+			require.NoError(t, json.Unmarshal(out.Bytes(), &rec))
+
+			assert.Equal(t, auditHelmUpgrade, rec.Action)
+			assert.Equal(t, id.String(), rec.LoadBalancerID)
+			assert.Equal(t, defaultClusterName, rec.Cluster)
+			assert.Equal(t, tcase.messageID, rec.MessageID)
+			assert.Equal(t, tcase.traceID, rec.TraceID)
+			assert.Equal(t, "before", rec.ValuesHashBefore)
+			assert.Equal(t, "after", rec.ValuesHashAfter)
+			assert.Equal(t, tcase.outcome, rec.Outcome)
+			assert.False(t, rec.Time.IsZero())
+
+			if tcase.err != nil {
+				assert.Equal(t, tcase.err.Error(), rec.Error)
+			}
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestHelmValuesHash() { //nolint:govet
+	values := func(creds string) map[string]interface{} {
+		return map[string]interface{}{
+			"operator": map[string]interface{}{
+				"managed": map[string]interface{}{
+					"lbID":            "loadbal-test",
+					dataPlaneCredsKey: creds,
+				},
+			},
+		}
+	}
+
+	first, second := values("first"), values("second")
+
+	assert.Equal(suite.T(), helmValuesHash(first), helmValuesHash(second), "generated credentials should not change the hash")
+	assert.NotEqual(suite.T(), helmValuesHash(first), helmValuesHash(map[string]interface{}{"replicas": 2}))
+	assert.Empty(suite.T(), helmValuesHash(nil))
+
+	// the values are deployed after they are hashed
+	assert.Equal(suite.T(), "first", first["operator"].(map[string]interface{})["managed"].(map[string]interface{})[dataPlaneCredsKey])
+}
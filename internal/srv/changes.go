@@ -12,7 +12,7 @@ func (s *Server) processLoadBalancerChangeCreate(ctx context.Context, lb *loadBa
 		return err
 	}
 
-	numberLoadBalancersCreatedGauge.Inc()
+	loadBalancersCreatedCounter.Inc()
 
 	return nil
 }
@@ -27,7 +27,7 @@ func (s *Server) processLoadBalancerChangeDelete(ctx context.Context, lb *loadBa
 		return err
 	}
 
-	numberLoadBalancersDeletedGauge.Inc()
+	loadBalancersDeletedCounter.Inc()
 
 	return nil
 }
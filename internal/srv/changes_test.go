@@ -50,9 +50,9 @@ func (suite *srvTestSuite) TestProcessLoadBalancerChangeCreate() { //nolint:gove
 		backoff.WithMaxRetries(5),
 	)
 
-	srv := Server{
+	srv := &Server{
 		APIClient:     lbapi.NewClient(api.URL),
-		BackoffConfig: backoffPolicy,
+		RetryPolicies: map[Operation]backoff.Policy{OpUpgrade: backoffPolicy},
 		Echo:          eSrv,
 		Context:       context.TODO(),
 		Logger:        zap.NewNop().Sugar(),
@@ -77,8 +77,8 @@ func (suite *srvTestSuite) TestProcessLoadBalancerChangeCreate() { //nolint:gove
 			},
 		},
 		{
-			name:           "invalid loadbalancer - long name",
-			expectedErrors: []error{errInvalidObjectNameLength},
+			name:           "loadbalancer create - long id",
+			expectedErrors: nil,
 			chart:          ch,
 			cfg:            suite.Kubeenv.Config,
 			msg: pubsubx.ChangeMessage{
@@ -140,9 +140,9 @@ func (suite *srvTestSuite) TestProcessLoadBalancerDelete() { //nolint:govet
 		backoff.WithMaxRetries(5),
 	)
 
-	srv := Server{
+	srv := &Server{
 		APIClient:     lbapi.NewClient(api.URL),
-		BackoffConfig: backoffPolicy,
+		RetryPolicies: map[Operation]backoff.Policy{OpUpgrade: backoffPolicy},
 		Echo:          eSrv,
 		Context:       context.TODO(),
 		Logger:        zap.NewNop().Sugar(),
@@ -225,7 +225,7 @@ func (suite *srvTestSuite) TestProcessLoadBalancerUpdate() { //nolint:govet
 
 	srv := Server{
 		APIClient:     lbapi.NewClient(api.URL),
-		BackoffConfig: backoffPolicy,
+		RetryPolicies: map[Operation]backoff.Policy{OpUpgrade: backoffPolicy},
 		KubeClient:    suite.Kubeenv.Config,
 		Echo:          eSrv,
 		Context:       context.TODO(),
@@ -0,0 +1,200 @@
+package srv
+
+import (
+	"context"
+	"fmt"
+
+	"go.infratographer.com/x/gidx"
+	"golang.org/x/exp/slices"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/record"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+)
+
+// defaultClusterName is the name reported for the cluster configured by KubeClient
+const defaultClusterName = "default"
+
+// Cluster is a kubernetes cluster that load balancers are deployed to for a set of locations
+type Cluster struct {
+	Name      string
+	Locations []string
+	Config    *rest.Config
+	Recorder  record.EventRecorder
+
+	broadcaster record.EventBroadcaster
+}
+
+// NewClusters loads the configured deployment clusters. Kubeconfigs stored in secrets are
+// read from the cluster described by home.
+func NewClusters(ctx context.Context, home *rest.Config, cfgs []config.ClusterConfig) ([]*Cluster, error) {
+	clusters := make([]*Cluster, 0, len(cfgs))
+
+	for _, cfg := range cfgs {
+		restConfig, err := clusterRESTConfig(ctx, home, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", cfg.Name, err)
+		}
+
+		broadcaster, recorder, err := NewEventRecorder(restConfig)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", cfg.Name, err)
+		}
+
+		clusters = append(clusters, &Cluster{
+			Name:        cfg.Name,
+			Locations:   cfg.Locations,
+			Config:      InstrumentConfig(restConfig),
+			Recorder:    recorder,
+			broadcaster: broadcaster,
+		})
+	}
+
+	return clusters, nil
+}
+
+// Shutdown stops recording events to the cluster
+func (c *Cluster) Shutdown() {
+	if c.broadcaster != nil {
+		c.broadcaster.Shutdown()
+	}
+}
+
+func clusterRESTConfig(ctx context.Context, home *rest.Config, cfg config.ClusterConfig) (*rest.Config, error) {
+	var (
+		kubeconfig *clientcmdapi.Config
+		err        error
+	)
+
+	switch {
+	case cfg.KubeconfigPath != "":
+		kubeconfig, err = clientcmd.LoadFromFile(cfg.KubeconfigPath)
+	case cfg.Secret.Name != "":
+		var kc *kubernetes.Clientset
+
+		if kc, err = kubernetes.NewForConfig(home); err == nil {
+			kubeconfig, err = kubeconfigFromSecret(ctx, kc, cfg.Secret)
+		}
+	default:
+		return nil, errInvalidClusterConfig
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return clientcmd.NewDefaultClientConfig(*kubeconfig, &clientcmd.ConfigOverrides{CurrentContext: cfg.Context}).ClientConfig()
+}
+
+func kubeconfigFromSecret(ctx context.Context, client kubernetes.Interface, ref config.SecretRefConfig) (*clientcmdapi.Config, error) {
+	secret, err := client.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	key := ref.Key
+	if key == "" {
+		key = "kubeconfig"
+	}
+
+	data, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: secret %s/%s has no key %s", errInvalidClusterConfig, ref.Namespace, ref.Name, key)
+	}
+
+	return clientcmd.Load(data)
+}
+
+type clusterKey struct{}
+
+// withCluster returns a context whose kubernetes operations target the cluster
+func withCluster(ctx context.Context, c *Cluster) context.Context {
+	if c == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, clusterKey{}, c)
+}
+
+func clusterFromContext(ctx context.Context) *Cluster {
+	c, _ := ctx.Value(clusterKey{}).(*Cluster)
+
+	return c
+}
+
+// restConfig returns the kubernetes config of the cluster targeted by the context,
+// falling back to KubeClient
+func (s *Server) restConfig(ctx context.Context) *rest.Config {
+	if c := clusterFromContext(ctx); c != nil {
+		return c.Config
+	}
+
+	return s.KubeClient
+}
+
+// eventRecorder returns the event recorder of the cluster targeted by the context
+func (s *Server) eventRecorder(ctx context.Context) record.EventRecorder {
+	if c := clusterFromContext(ctx); c != nil && c.Recorder != nil {
+		return c.Recorder
+	}
+
+	return s.EventRecorder
+}
+
+// clusterName returns the name of the cluster targeted by the context
+func clusterName(ctx context.Context) string {
+	if c := clusterFromContext(ctx); c != nil {
+		return c.Name
+	}
+
+	return defaultClusterName
+}
+
+// clusterFor returns the cluster a loadbalancer is deployed to. Deletes do not carry
+// loadbalancer data so the location is taken from the additional subjects of the message.
+// Nil is returned for loadbalancers deployed to the default cluster.
+func (s *Server) clusterFor(lb *loadBalancer, adds []gidx.PrefixedID) *Cluster {
+	locations := make([]string, 0, len(adds)+1)
+
+	if lb.lbData != nil {
+		locations = append(locations, lb.lbData.Location.ID)
+	}
+
+	for _, id := range adds {
+		locations = append(locations, id.String())
+	}
+
+	for _, c := range s.Clusters {
+		for _, location := range locations {
+			if slices.Contains(c.Locations, location) {
+				return c
+			}
+		}
+	}
+
+	return nil
+}
+
+// clusterCheck reports whether the cluster API server can be reached and the operator
+// has the permissions it needs in the cluster
+func clusterCheck(c *Cluster) func(context.Context) error {
+	return func(ctx context.Context) error {
+		kc, err := kubernetes.NewForConfig(c.Config)
+		if err == nil {
+			_, err = kc.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+		}
+
+		if err != nil {
+			clusterUpGauge.WithLabelValues(c.Name).Set(0)
+			return err
+		}
+
+		clusterUpGauge.WithLabelValues(c.Name).Set(1)
+
+		return checkPermissions(ctx, kc)
+	}
+}
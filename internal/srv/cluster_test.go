@@ -0,0 +1,126 @@
+package srv
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/gidx"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: east
+  cluster:
+    server: https://east.example.com
+- name: west
+  cluster:
+    server: https://west.example.com
+contexts:
+- name: east
+  context:
+    cluster: east
+    user: operator
+- name: west
+  context:
+    cluster: west
+    user: operator
+current-context: east
+users:
+- name: operator
+  user:
+    token: secret
+`
+
+func (suite *srvTestSuite) TestClusterFor() { //nolint:govet
+	type testCase struct {
+		name          string
+		lbData        *lbapi.LoadBalancer
+		adds          []gidx.PrefixedID
+		expectCluster string
+	}
+
+	east := &Cluster{Name: "east", Locations: []string{"lctnloc-east"}, Config: &rest.Config{Host: "https://east.example.com"}}
+	west := &Cluster{Name: "west", Locations: []string{"lctnloc-west"}, Config: &rest.Config{Host: "https://west.example.com"}}
+
+	testCases := []testCase{
+		{
+			name:          "location from loadbalancer",
+			lbData:        &lbapi.LoadBalancer{Location: lbapi.LocationNode{ID: "lctnloc-west"}},
+			expectCluster: "west",
+		},
+		{
+			name:          "location from delete message",
+			adds:          []gidx.PrefixedID{"tnntten-owner", "lctnloc-east"},
+			expectCluster: "east",
+		},
+		{
+			name:          "default cluster",
+			lbData:        &lbapi.LoadBalancer{Location: lbapi.LocationNode{ID: "lctnloc-north"}},
+			expectCluster: defaultClusterName,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			srv := Server{
+				KubeClient: &rest.Config{Host: "https://default.example.com"},
+				Clusters:   []*Cluster{east, west},
+			}
+
+			lb := &loadBalancer{loadBalancerID: gidx.MustNewID(LBPrefix), lbData: tc.lbData}
+			ctx := withCluster(context.TODO(), srv.clusterFor(lb, tc.adds))
+
+			assert.Equal(t, tc.expectCluster, clusterName(ctx))
+
+			if tc.expectCluster == defaultClusterName {
+				assert.Equal(t, srv.KubeClient, srv.restConfig(ctx))
+			} else {
+				assert.Equal(t, "https://"+tc.expectCluster+".example.com", srv.restConfig(ctx).Host)
+			}
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestClusterRESTConfig() { //nolint:govet
+	dir := suite.T().TempDir()
+	path := filepath.Join(dir, "kubeconfig")
+
+	require.NoError(suite.T(), os.WriteFile(path, []byte(testKubeconfig), 0o600))
+
+	cfg, err := clusterRESTConfig(context.TODO(), nil, config.ClusterConfig{Name: "east", KubeconfigPath: path})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "https://east.example.com", cfg.Host)
+
+	cfg, err = clusterRESTConfig(context.TODO(), nil, config.ClusterConfig{Name: "west", KubeconfigPath: path, Context: "west"})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "https://west.example.com", cfg.Host)
+
+	_, err = clusterRESTConfig(context.TODO(), nil, config.ClusterConfig{Name: "none"})
+	assert.ErrorIs(suite.T(), err, errInvalidClusterConfig)
+}
+
+func (suite *srvTestSuite) TestKubeconfigFromSecret() { //nolint:govet
+	client := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "east-kubeconfig", Namespace: "default"},
+		Data:       map[string][]byte{"kubeconfig": []byte(testKubeconfig)},
+	})
+
+	kubeconfig, err := kubeconfigFromSecret(context.TODO(), client, config.SecretRefConfig{Namespace: "default", Name: "east-kubeconfig"})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "east", kubeconfig.CurrentContext)
+
+	_, err = kubeconfigFromSecret(context.TODO(), client, config.SecretRefConfig{Namespace: "default", Name: "east-kubeconfig", Key: "missing"})
+	assert.ErrorIs(suite.T(), err, errInvalidClusterConfig)
+}
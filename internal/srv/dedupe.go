@@ -0,0 +1,178 @@
+package srv
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+)
+
+// DefaultDedupeCacheSize is the number of message keys remembered in memory
+const DefaultDedupeCacheSize = 4096
+
+// DedupeCache tracks messages that have already been handed to a runner so that
+// redelivered or duplicate messages do not trigger the same work twice. Keys are
+// always held in an in-memory LRU and, when a NATS KV bucket is provided, also
+// stored in the bucket so that they survive restarts and are shared between replicas.
+// The key of a message is released when its task fails so the message can be retried.
+type DedupeCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+	kv    nats.KeyValue
+}
+
+// NewDedupeCache returns an in-memory DedupeCache holding up to size keys
+func NewDedupeCache(size int) *DedupeCache {
+	if size <= 0 {
+		size = DefaultDedupeCacheSize
+	}
+
+	return &DedupeCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// NewKVDedupeCache returns a DedupeCache backed by the provided NATS KV bucket
+func NewKVDedupeCache(size int, kv nats.KeyValue) *DedupeCache {
+	d := NewDedupeCache(size)
+	d.kv = kv
+
+	return d
+}
+
+// NewDedupeKV creates or binds to the NATS KV bucket used to store message keys.
+// Keys expire from the bucket after ttl.
+func NewDedupeKV(conn events.Connection, bucket string, ttl time.Duration) (nats.KeyValue, error) {
+	return newKeyValue(conn, &nats.KeyValueConfig{
+		Bucket:      bucket,
+		Description: "load-balancer-operator processed message keys",
+		TTL:         ttl,
+	})
+}
+
+// newKeyValue binds to the NATS KV bucket, creating it from cfg when it does not exist
+func newKeyValue(conn events.Connection, cfg *nats.KeyValueConfig) (nats.KeyValue, error) {
+	nc, ok := conn.Source().(*nats.Conn)
+	if !ok {
+		return nil, errKVUnsupported
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, errors.Join(err, errKVUnsupported)
+	}
+
+	kv, err := js.KeyValue(cfg.Bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(cfg)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return kv, nil
+}
+
+// claim records the key and reports whether it was newly recorded. A false
+// result means the key has already been claimed and the message is a duplicate.
+// A nil DedupeCache claims every key.
+func (d *DedupeCache) claim(key string) bool {
+	if d == nil {
+		return true
+	}
+
+	d.mu.Lock()
+
+	if el, ok := d.items[key]; ok {
+		d.order.MoveToFront(el)
+		d.mu.Unlock()
+
+		return false
+	}
+
+	// the key is held locally while the bucket is consulted, so listeners are not
+	// blocked on the round trip and a concurrent delivery is still caught
+	d.add(key)
+	d.mu.Unlock()
+
+	if d.kv == nil {
+		return true
+	}
+
+	// the bucket is best effort; any error other than an existing key falls back to the local cache
+	_, err := d.kv.Create(kvKey(key), []byte(time.Now().UTC().Format(time.RFC3339)))
+
+	return !errors.Is(err, nats.ErrKeyExists)
+}
+
+// release forgets a claimed key so that a later delivery of the message is processed
+func (d *DedupeCache) release(key string) {
+	if d == nil || key == "" {
+		return
+	}
+
+	d.mu.Lock()
+
+	if el, ok := d.items[key]; ok {
+		d.order.Remove(el)
+		delete(d.items, key)
+	}
+
+	d.mu.Unlock()
+
+	if d.kv != nil {
+		_ = d.kv.Delete(kvKey(key))
+	}
+}
+
+func (d *DedupeCache) add(key string) {
+	d.items[key] = d.order.PushFront(key)
+
+	for d.order.Len() > d.size {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.items, oldest.Value.(string))
+	}
+}
+
+// kvKey hashes a message key into the character set allowed for NATS KV keys
+func kvKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// dedupeKey returns the key used to detect duplicate messages. Messages that carry
+// a timestamp are keyed on (subject, event type, timestamp) so that the same change
+// published twice is caught; otherwise the stable message ID is used.
+func dedupeKey[T any](msg events.Message[T], subj gidx.PrefixedID, eventType string, ts time.Time) string {
+	if !ts.IsZero() {
+		return fmt.Sprintf("%s|%s|%d", subj, eventType, ts.UnixNano())
+	}
+
+	return messageID(msg)
+}
+
+// messageID returns an ID that is stable across redeliveries of a message. NATS
+// consumer sequences change on every delivery, so the stream sequence is preferred.
+func messageID[T any](msg events.Message[T]) string {
+	if nm, ok := msg.Source().(*nats.Msg); ok {
+		if md, err := nm.Metadata(); err == nil {
+			return fmt.Sprintf("%s/%d", md.Stream, md.Sequence.Stream)
+		}
+	}
+
+	return msg.Topic() + "/" + msg.ID()
+}
@@ -0,0 +1,93 @@
+package srv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+)
+
+func (suite *srvTestSuite) TestDedupeCache() { //nolint:govet
+	type testCase struct {
+		name   string
+		size   int
+		claims []string
+		expect []bool
+	}
+
+	testCases := []testCase{
+		{
+			name:   "unique keys",
+			size:   10,
+			claims: []string{"a", "b", "c"},
+			expect: []bool{true, true, true},
+		},
+		{
+			name:   "duplicate key",
+			size:   10,
+			claims: []string{"a", "b", "a"},
+			expect: []bool{true, true, false},
+		},
+		{
+			name:   "evicted key is claimable again",
+			size:   2,
+			claims: []string{"a", "b", "c", "a"},
+			expect: []bool{true, true, true, true},
+		},
+		{
+			name:   "recently used key is kept",
+			size:   2,
+			claims: []string{"a", "b", "a", "c", "a"},
+			expect: []bool{true, true, false, true, false},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			d := NewDedupeCache(tc.size)
+
+			for i, key := range tc.claims {
+				assert.Equal(t, tc.expect[i], d.claim(key), "claim %d of %s", i, key)
+			}
+		})
+	}
+
+	suite.T().Run("released key is claimable again", func(t *testing.T) {
+		d := NewDedupeCache(10)
+
+		assert.True(t, d.claim("a"))
+		d.release("a")
+		assert.True(t, d.claim("a"))
+	})
+
+	suite.T().Run("nil cache claims everything", func(t *testing.T) {
+		var d *DedupeCache
+
+		assert.True(t, d.claim("a"))
+		assert.True(t, d.claim("a"))
+		d.release("a")
+	})
+}
+
+func (suite *srvTestSuite) TestDedupeKey() { //nolint:govet
+	id := gidx.MustNewID("loadbal")
+	ts := time.Now()
+
+	msg, err := suite.Connection.PublishChange(context.TODO(), "dedupe-key", events.ChangeMessage{
+		EventType: string(events.UpdateChangeType),
+		SubjectID: id,
+	})
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	withTS := dedupeKey(msg, id, string(events.UpdateChangeType), ts)
+	assert.Equal(suite.T(), withTS, dedupeKey(msg, id, string(events.UpdateChangeType), ts))
+	assert.NotEqual(suite.T(), withTS, dedupeKey(msg, id, string(events.DeleteChangeType), ts))
+	assert.NotEqual(suite.T(), withTS, dedupeKey(msg, id, string(events.UpdateChangeType), ts.Add(time.Nanosecond)))
+
+	assert.Equal(suite.T(), messageID(msg), dedupeKey(msg, id, string(events.UpdateChangeType), time.Time{}))
+}
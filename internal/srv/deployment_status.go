@@ -0,0 +1,173 @@
+package srv
+
+import (
+	"context"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	deploymentStatusKind = "LoadBalancerDeployment"
+
+	deploymentPhaseReconciling = "Reconciling"
+	deploymentPhaseReady       = "Ready"
+	deploymentPhaseFailed      = "Failed"
+	deploymentPhaseDeleting    = "Deleting"
+
+	conditionReady       = "Ready"
+	conditionReconciling = "Reconciling"
+)
+
+// deploymentStatusGVR is the LoadBalancerDeployment custom resource that mirrors the state
+// of each loadbalancer in its namespace
+var deploymentStatusGVR = schema.GroupVersionResource{
+	Group:    "lb-operator.infratographer.com",
+	Version:  "v1alpha1",
+	Resource: "loadbalancerdeployments",
+}
+
+// lbDeploymentStatus is the status of a LoadBalancerDeployment resource
+type lbDeploymentStatus struct {
+	LoadBalancerID string             `json:"loadBalancerID"`
+	Location       string             `json:"location,omitempty"`
+	Ports          []int64            `json:"ports,omitempty"`
+	IPAddresses    []string           `json:"ipAddresses,omitempty"`
+	ChartVersion   string             `json:"chartVersion,omitempty"`
+	Phase          string             `json:"phase,omitempty"`
+	LastEvent      string             `json:"lastEvent,omitempty"`
+	LastMessageID  string             `json:"lastMessageID,omitempty"`
+	LastError      string             `json:"lastError,omitempty"`
+	UpdatedAt      metav1.Time        `json:"updatedAt"`
+	Conditions     []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// setDeploymentStatus records the processing stage of a loadbalancer on its
+// LoadBalancerDeployment resource. Failures are logged and do not fail the task, the
+// resource cannot be written before the namespace exists or once it is terminating.
+func (s *Server) setDeploymentStatus(ctx context.Context, lb *loadBalancer, event, phase string, taskErr error) {
+	if !s.DeploymentStatus {
+		return
+	}
+
+	names, err := s.lbNames(ctx, lb)
+	if err != nil {
+		s.logger(ctx).Debugw("unable to resolve namespace for deployment status", "error", err, "loadBalancer", lb.loadBalancerID.String())
+		return
+	}
+
+	client, err := dynamic.NewForConfig(s.restConfig(ctx))
+	if err != nil {
+		s.logger(ctx).Debugw("unable to authenticate against kubernetes cluster", "error", err)
+		return
+	}
+
+	res := client.Resource(deploymentStatusGVR).Namespace(names.namespace)
+
+	status := lbDeploymentStatus{}
+
+	if current, err := res.Get(ctx, names.release, metav1.GetOptions{}); err == nil {
+		if raw, ok := current.Object["status"].(map[string]interface{}); ok {
+			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &status)
+		}
+	}
+
+	s.nextDeploymentStatus(&status, lb, event, messageIDFromContext(ctx), phase, taskErr)
+
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		s.logger(ctx).Debugw("unable to encode deployment status", "error", err, "loadBalancer", lb.loadBalancerID.String())
+		return
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": raw,
+	}}
+	obj.SetAPIVersion(deploymentStatusGVR.GroupVersion().String())
+	obj.SetKind(deploymentStatusKind)
+	obj.SetName(names.release)
+	obj.SetNamespace(names.namespace)
+	obj.SetLabels(map[string]string{managedLabel: "true"})
+	obj.SetAnnotations(map[string]string{lbIDAnnotation: lb.loadBalancerID.String()})
+
+	if _, err := res.Apply(ctx, names.release, obj, metav1.ApplyOptions{FieldManager: "loadbalanceroperator", Force: true}); err != nil {
+		s.logger(ctx).Debugw("unable to update deployment status", "error", err, "loadBalancer", lb.loadBalancerID.String(), "namespace", names.namespace, "phase", phase)
+	}
+}
+
+// nextDeploymentStatus updates status for the stage a loadbalancer has reached. Fields that
+// are unknown for the stage, such as the ports of a deleted loadbalancer, are left as they were.
+func (s *Server) nextDeploymentStatus(status *lbDeploymentStatus, lb *loadBalancer, event, messageID, phase string, taskErr error) {
+	status.LoadBalancerID = lb.loadBalancerID.String()
+	status.Phase = phase
+	status.LastEvent = event
+	status.LastMessageID = messageID
+	status.UpdatedAt = metav1.Now()
+
+	if lb.lbData != nil {
+		status.Location = lb.lbData.Location.ID
+		status.Ports = nil
+		status.IPAddresses = nil
+
+		for _, port := range lb.lbData.Ports.Edges {
+			status.Ports = append(status.Ports, port.Node.Number)
+		}
+
+		sort.Slice(status.Ports, func(i, j int) bool { return status.Ports[i] < status.Ports[j] })
+
+		for _, ip := range lb.lbData.IPAddresses {
+			status.IPAddresses = append(status.IPAddresses, ip.IP)
+		}
+	}
+
+	switch phase {
+	case deploymentPhaseReconciling, deploymentPhaseDeleting:
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    conditionReconciling,
+			Status:  metav1.ConditionTrue,
+			Reason:  phase,
+			Message: "processing " + event + " event",
+		})
+	case deploymentPhaseReady:
+		status.LastError = ""
+
+		if s.Chart != nil && s.Chart.Metadata != nil {
+			status.ChartVersion = s.Chart.Metadata.Version
+		}
+
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    conditionReconciling,
+			Status:  metav1.ConditionFalse,
+			Reason:  phase,
+			Message: "processed " + event + " event",
+		})
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    conditionReady,
+			Status:  metav1.ConditionTrue,
+			Reason:  phase,
+			Message: "loadbalancer is deployed",
+		})
+	case deploymentPhaseFailed:
+		if taskErr != nil {
+			status.LastError = taskErr.Error()
+		}
+
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    conditionReconciling,
+			Status:  metav1.ConditionFalse,
+			Reason:  phase,
+			Message: "failed to process " + event + " event",
+		})
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    conditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  phase,
+			Message: status.LastError,
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package srv
+
+import (
+	"errors"
+
+	"github.com/stretchr/testify/assert"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/gidx"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+func (suite *srvTestSuite) TestNextDeploymentStatus() { //nolint:govet
+	id := gidx.MustNewID(LBPrefix)
+
+	lb := &loadBalancer{
+		loadBalancerID: id,
+		lbData: &lbapi.LoadBalancer{
+			ID:          id.String(),
+			Location:    lbapi.LocationNode{ID: "lctnloc-testing"},
+			IPAddresses: []lbapi.IPAddress{{IP: "192.0.2.1"}},
+			Ports: lbapi.Ports{Edges: []lbapi.PortEdges{
+				{Node: lbapi.PortNode{Number: 443}},
+				{Node: lbapi.PortNode{Number: 80}},
+			}},
+		},
+	}
+
+	srv := Server{Chart: &chart.Chart{Metadata: &chart.Metadata{Version: "1.2.3"}}}
+	status := lbDeploymentStatus{}
+
+	srv.nextDeploymentStatus(&status, lb, "create", "stream/1", deploymentPhaseReconciling, nil)
+
+	assert.Equal(suite.T(), id.String(), status.LoadBalancerID)
+	assert.Equal(suite.T(), "lctnloc-testing", status.Location)
+	assert.Equal(suite.T(), []int64{80, 443}, status.Ports)
+	assert.Equal(suite.T(), []string{"192.0.2.1"}, status.IPAddresses)
+	assert.Equal(suite.T(), "stream/1", status.LastMessageID)
+	assert.True(suite.T(), meta.IsStatusConditionTrue(status.Conditions, conditionReconciling))
+	assert.Nil(suite.T(), meta.FindStatusCondition(status.Conditions, conditionReady))
+
+	srv.nextDeploymentStatus(&status, lb, "create", "stream/1", deploymentPhaseFailed, errors.New("helm failed")) //nolint:goerr113
+
+	assert.Equal(suite.T(), deploymentPhaseFailed, status.Phase)
+	assert.Equal(suite.T(), "helm failed", status.LastError)
+	assert.True(suite.T(), meta.IsStatusConditionFalse(status.Conditions, conditionReady))
+	assert.True(suite.T(), meta.IsStatusConditionFalse(status.Conditions, conditionReconciling))
+
+	// deletes do not carry loadbalancer data, the last known values are kept
+	deleted := &loadBalancer{loadBalancerID: id}
+
+	srv.nextDeploymentStatus(&status, lb, "update", "stream/2", deploymentPhaseReady, nil)
+	srv.nextDeploymentStatus(&status, deleted, "delete", "stream/3", deploymentPhaseDeleting, nil)
+
+	assert.Equal(suite.T(), deploymentPhaseDeleting, status.Phase)
+	assert.Empty(suite.T(), status.LastError)
+	assert.Equal(suite.T(), "1.2.3", status.ChartVersion)
+	assert.Equal(suite.T(), "lctnloc-testing", status.Location)
+	assert.Equal(suite.T(), []int64{80, 443}, status.Ports)
+	assert.True(suite.T(), meta.IsStatusConditionTrue(status.Conditions, conditionReady))
+	assert.True(suite.T(), meta.IsStatusConditionTrue(status.Conditions, conditionReconciling))
+}
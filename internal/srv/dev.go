@@ -0,0 +1,51 @@
+package srv
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.infratographer.com/x/events"
+)
+
+// devRoutes registers the endpoints used to publish messages to the operator in dev mode.
+// They are not authenticated and only registered when DevPublisher is set.
+func (s *Server) devRoutes(g *echo.Group) {
+	g.POST("/changes/:topic", s.devPublishChangeHandler)
+	g.POST("/events/:topic", s.devPublishEventHandler)
+}
+
+// devPublishChangeHandler publishes the change message in the request body to a topic
+func (s *Server) devPublishChangeHandler(c echo.Context) error {
+	msg := events.ChangeMessage{}
+	if err := c.Bind(&msg); err != nil {
+		return err
+	}
+
+	published, err := s.DevPublisher.PublishChange(c.Request().Context(), c.Param("topic"), msg)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusAccepted, echo.Map{
+		"id":    published.ID(),
+		"topic": published.Topic(),
+	})
+}
+
+// devPublishEventHandler publishes the event message in the request body to a topic
+func (s *Server) devPublishEventHandler(c echo.Context) error {
+	msg := events.EventMessage{}
+	if err := c.Bind(&msg); err != nil {
+		return err
+	}
+
+	published, err := s.DevPublisher.PublishEvent(c.Request().Context(), c.Param("topic"), msg)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusAccepted, echo.Map{
+		"id":    published.ID(),
+		"topic": published.Topic(),
+	})
+}
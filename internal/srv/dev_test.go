@@ -0,0 +1,79 @@
+package srv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/echox"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/load-balancer-operator/internal/memevents"
+)
+
+func (suite *srvTestSuite) TestDevPublish() { //nolint:govet
+	e, err := echox.NewServer(zap.NewNop(), echox.Config{}, nil)
+	require.NoError(suite.T(), err, "unexpected error creating new server")
+
+	conn := memevents.NewConnection()
+
+	s := &Server{
+		Echo:             e,
+		Context:          context.TODO(),
+		Logger:           zap.NewNop().Sugar(),
+		EventsConnection: conn,
+		DevPublisher:     conn,
+		Locations:        []string{"lctnloc-tracked"},
+	}
+
+	s.Echo.AddHandler(s)
+
+	changes, err := conn.SubscribeChanges(context.TODO(), "*.load-balancer")
+	require.NoError(suite.T(), err)
+
+	id := gidx.MustNewID(LBPrefix)
+
+	type testCase struct {
+		name     string
+		path     string
+		body     string
+		status   int
+		contains string
+	}
+
+	testCases := []testCase{
+		{name: "invalid change", path: "/dev/changes/load-balancer", body: `{"eventType": "create"}`, status: http.StatusBadRequest},
+		{name: "invalid event", path: "/dev/events/ip-address", body: `{"subjectID": "ipamipa-test"}`, status: http.StatusBadRequest},
+		{name: "change", path: "/dev/changes/load-balancer", body: `{"subjectID": "` + id.String() + `", "eventType": "create"}`, status: http.StatusAccepted, contains: `"topic":"changes.create.load-balancer"`},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tcase.path, strings.NewReader(tcase.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rec := httptest.NewRecorder()
+			s.Echo.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tcase.status, rec.Code, rec.Body.String())
+			assert.Contains(t, rec.Body.String(), tcase.contains)
+		})
+	}
+
+	select {
+	case msg := <-changes:
+		assert.Equal(suite.T(), id, msg.Message().SubjectID)
+
+		// messages for untracked locations are acked without being processed
+		s.processChange(msg)
+		assert.Equal(suite.T(), 0, conn.Pending())
+	case <-time.After(time.Second):
+		assert.Fail(suite.T(), "published change not received")
+	}
+}
@@ -0,0 +1,282 @@
+package srv
+
+import (
+	"errors"
+	"time"
+
+	lbmeta "go.infratographer.com/load-balancer-api/pkg/metadata"
+	"go.infratographer.com/x/events"
+	v1 "k8s.io/api/core/v1"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+)
+
+const (
+	// anySubject matches every subject prefix when registering a handler
+	anySubject = "*"
+
+	// reconcileEventType is the event of tasks submitted through the admin API to
+	// redeploy a loadbalancer
+	reconcileEventType = "reconcile"
+)
+
+// precondition is checked before a handler runs. Returning an error skips the task
+// and the error is logged as the reason the event was ignored.
+type precondition func(t *lbTask) error
+
+// taskHandler processes a task for a registered event type and subject prefix
+type taskHandler struct {
+	name          string
+	preconditions []precondition
+	handle        func(t *lbTask) error
+}
+
+type handlerKey struct {
+	eventType     string
+	subjectPrefix string
+}
+
+// handlerRegistry routes tasks to handlers by event type and subject prefix
+type handlerRegistry struct {
+	handlers map[handlerKey]*taskHandler
+}
+
+func newHandlerRegistry() *handlerRegistry {
+	return &handlerRegistry{
+		handlers: make(map[handlerKey]*taskHandler),
+	}
+}
+
+// register adds a handler for the event type and subject prefix. Use anySubject to
+// handle the event type for subjects that do not have a more specific handler.
+func (r *handlerRegistry) register(eventType, subjectPrefix string, h *taskHandler) {
+	r.handlers[handlerKey{eventType: eventType, subjectPrefix: subjectPrefix}] = h
+}
+
+// lookup returns the handler for the event type and subject prefix, falling back to
+// the handler registered for anySubject
+func (r *handlerRegistry) lookup(eventType, subjectPrefix string) (*taskHandler, bool) {
+	if h, ok := r.handlers[handlerKey{eventType: eventType, subjectPrefix: subjectPrefix}]; ok {
+		return h, true
+	}
+
+	h, ok := r.handlers[handlerKey{eventType: eventType, subjectPrefix: anySubject}]
+
+	return h, ok
+}
+
+// taskHandlers holds the handlers used by process. Supporting a new event type
+// means registering a handler in registerDefaultHandlers.
+var taskHandlers = registerDefaultHandlers(newHandlerRegistry())
+
+func registerDefaultHandlers(r *handlerRegistry) *handlerRegistry {
+	update := &taskHandler{
+		name:          "update",
+		preconditions: []precondition{notTerminating},
+		handle:        handleUpdate,
+	}
+
+	r.register(string(events.CreateChangeType), LBPrefix, &taskHandler{
+		name:          "create",
+		preconditions: []precondition{notTerminating},
+		handle:        handleCreate,
+	})
+	r.register(reconcileEventType, LBPrefix, &taskHandler{
+		name:          "reconcile",
+		preconditions: []precondition{notTerminating},
+		handle:        handleUpdate,
+	})
+	r.register(string(events.DeleteChangeType), LBPrefix, &taskHandler{
+		name:   "delete",
+		handle: handleDelete,
+	})
+
+	// changes to objects associated with a loadbalancer (ports, pools, origins, etc.)
+	// result in the loadbalancer being updated
+	r.register(string(events.CreateChangeType), anySubject, update)
+	r.register(string(events.UpdateChangeType), anySubject, update)
+	r.register(string(events.DeleteChangeType), anySubject, update)
+
+	r.register("ip-address.assigned", anySubject, &taskHandler{
+		name:          "ip-address.assigned",
+		preconditions: []precondition{notTerminating},
+		handle:        handleIPAssigned,
+	})
+	r.register("ip-address.unassigned", anySubject, &taskHandler{
+		name:   "ip-address.unassigned",
+		handle: handleIPUnassigned,
+	})
+
+	return r
+}
+
+// notTerminating skips tasks for loadbalancers that are being terminated
+func notTerminating(t *lbTask) error {
+	status := t.loadBalancerStatus()
+	if status != nil && status.State == lbmeta.LoadBalancerStateTerminating {
+		return errLoadBalancerTerminating
+	}
+
+	return nil
+}
+
+// loadBalancerStatus returns the loadbalancer state recorded by the load-balancer-api.
+// Nil is returned when the state cannot be determined.
+func (t *lbTask) loadBalancerStatus() *lbmeta.LoadBalancerStatus {
+	if t.lb.lbData == nil {
+		return nil
+	}
+
+	status, err := lbmeta.GetLoadbalancerStatus(t.lb.lbData.Metadata.Statuses, config.AppConfig.Metadata.StatusNamespaceID, lbmeta.LoadBalancerAPISource)
+	if err != nil {
+		// note the loadbalancer state is off/amiss, continue processing event
+		t.logger().Warnw("failed to find loadbalancer state", "error", err)
+		return nil
+	}
+
+	return status
+}
+
+func handleCreate(t *lbTask) error {
+	t.logger().Debugw("creating loadbalancer")
+
+	_ = t.reportStatus(lbmeta.LoadBalancerStateCreating, statusReasonCreating, "creating loadbalancer", nil)
+
+	if err := t.srv.processLoadBalancerChangeCreate(t.ctx, t.lb); err != nil {
+		t.logger().Errorw("handler unable to create loadbalancer", "error", err)
+		_ = t.reportStatus(loadBalancerStateFailed, statusReasonCreateFailed, "unable to create loadbalancer", err)
+
+		return err
+	}
+
+	return t.reportStatus(lbmeta.LoadBalancerStateActive, statusReasonCreated, "loadbalancer created", nil)
+}
+
+func handleDelete(t *lbTask) error {
+	t.logger().Debugw("deleting loadbalancer")
+
+	_ = t.reportStatus(loadBalancerStateDeleting, statusReasonDeleting, "deleting loadbalancer", nil)
+
+	if err := t.srv.processLoadBalancerChangeDelete(t.ctx, t.lb); err != nil {
+		t.logger().Errorw("handler unable to delete loadbalancer", "error", err)
+
+		// the loadbalancer remains deleting until its namespace is gone
+		if errors.Is(err, errPhaseTimeout) || errors.Is(err, errNamespaceStuck) {
+			_ = t.reportStatus(loadBalancerStateDeleting, statusReasonDeleteIncomplete, "loadbalancer removal has not completed", err)
+			return err
+		}
+	}
+
+	if err := t.reportStatus(lbmeta.LoadBalancerStateDeleted, statusReasonDeleted, "loadbalancer deleted", nil); err != nil {
+		return err
+	}
+
+	if ch := t.srv.removeRunner(t.lb.loadBalancerID.String()); ch != nil {
+		ch.stop()
+	}
+
+	return nil
+}
+
+func handleUpdate(t *lbTask) error {
+	t.logger().Debugw("updating loadbalancer")
+
+	_ = t.reportStatus(lbmeta.LoadBalancerStateUpdating, statusReasonUpdating, "updating loadbalancer", nil)
+
+	if err := t.srv.processLoadBalancerChangeUpdate(t.ctx, t.lb); err != nil {
+		t.logger().Errorw("handler unable to update loadbalancer", "error", err)
+		_ = t.reportStatus(loadBalancerStateDegraded, statusReasonUpdateFailed, "unable to update loadbalancer", err)
+
+		return err
+	}
+
+	return t.reportStatus(lbmeta.LoadBalancerStateActive, statusReasonUpdated, "loadbalancer updated", nil)
+}
+
+func handleIPAssigned(t *lbTask) error {
+	t.logger().Debugw("ip address processed. updating loadbalancer")
+
+	_ = t.reportStatus(lbmeta.LoadBalancerStateUpdating, statusReasonAssigningIP, "assigning ip address", nil)
+
+	if err := t.srv.createDeployment(t.ctx, t.lb); err != nil {
+		t.logger().Errorw("unable to update loadbalancer", "error", err)
+		_ = t.reportStatus(loadBalancerStateDegraded, statusReasonIPAssignFailed, "unable to assign ip address", err)
+
+		return err
+	}
+
+	return t.reportStatus(lbmeta.LoadBalancerStateActive, statusReasonIPAssigned, "ip address assigned", nil)
+}
+
+func handleIPUnassigned(t *lbTask) error {
+	t.logger().Debugw("ip address unassigned. updating loadbalancer")
+
+	return nil
+}
+
+// process routes a task to the handler registered for its event type and subject
+func process(t *lbTask) {
+	taskHandlers.dispatch(t)
+}
+
+// plan returns the handler that runs the task. An error is returned with the reason the
+// task is skipped when no handler is registered or one of its preconditions fails.
+func (r *handlerRegistry) plan(t *lbTask) (*taskHandler, error) {
+	h, ok := r.lookup(t.evt, t.subj.Prefix())
+	if !ok {
+		return nil, errNoHandler
+	}
+
+	for _, check := range h.preconditions {
+		if err := check(t); err != nil {
+			return h, err
+		}
+	}
+
+	return h, nil
+}
+
+// dispatch runs the handler registered for the task once all of its preconditions pass
+func (r *handlerRegistry) dispatch(t *lbTask) {
+	h, err := r.plan(t)
+
+	switch {
+	case errors.Is(err, errNoHandler):
+		t.logger().Warnw("no handler registered for event, skipping", "subjectID", t.subj.String())
+		unknownEventsCounter.WithLabelValues(t.evt).Inc()
+
+		return
+	case err != nil:
+		t.logger().Infow("ignoring event", "handler", h.name, "reason", err)
+		t.srv.recordEvent(t.ctx, t.lb, v1.EventTypeNormal, eventReasonSkipped, "ignored %s event for %s: %s", t.evt, t.subj, err)
+
+		return
+	}
+
+	phase := deploymentPhaseReconciling
+	if t.isDelete() {
+		phase = deploymentPhaseDeleting
+	}
+
+	t.srv.setDeploymentStatus(t.ctx, t.lb, t.evt, phase, nil)
+
+	start := time.Now()
+	err = h.handle(t)
+	t.err = err
+
+	taskDuration.WithLabelValues(h.name, resultLabel(err)).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		t.logger().Debugw("task failed", "error", err, "handler", h.name, "retryable", isRetryable(err))
+		t.srv.recordEvent(t.ctx, t.lb, v1.EventTypeWarning, eventReasonFailed, "%s of %s event for %s failed: %s", h.name, t.evt, t.subj, err)
+		t.srv.setDeploymentStatus(t.ctx, t.lb, t.evt, deploymentPhaseFailed, err)
+		t.srv.Dedupe.release(t.dedupeKey)
+
+		return
+	}
+
+	// the resource is removed along with the namespace of a deleted loadbalancer
+	if !t.isDelete() {
+		t.srv.setDeploymentStatus(t.ctx, t.lb, t.evt, deploymentPhaseReady, nil)
+	}
+}
@@ -0,0 +1,178 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+func (suite *srvTestSuite) TestHandlerRegistryLookup() { //nolint:govet
+	type testCase struct {
+		name          string
+		eventType     string
+		subjectPrefix string
+		expectHandler string
+		expectFound   bool
+	}
+
+	testCases := []testCase{
+		{
+			name:          "create loadbalancer",
+			eventType:     string(events.CreateChangeType),
+			subjectPrefix: LBPrefix,
+			expectHandler: "create",
+			expectFound:   true,
+		},
+		{
+			name:          "create port updates loadbalancer",
+			eventType:     string(events.CreateChangeType),
+			subjectPrefix: "loadprt",
+			expectHandler: "update",
+			expectFound:   true,
+		},
+		{
+			name:          "delete loadbalancer",
+			eventType:     string(events.DeleteChangeType),
+			subjectPrefix: LBPrefix,
+			expectHandler: "delete",
+			expectFound:   true,
+		},
+		{
+			name:          "delete origin updates loadbalancer",
+			eventType:     string(events.DeleteChangeType),
+			subjectPrefix: "loadogn",
+			expectHandler: "update",
+			expectFound:   true,
+		},
+		{
+			name:          "ip address assigned",
+			eventType:     "ip-address.assigned",
+			subjectPrefix: "ipamipa",
+			expectHandler: "ip-address.assigned",
+			expectFound:   true,
+		},
+		{
+			name:          "unknown event type",
+			eventType:     "certificate.rotated",
+			subjectPrefix: LBPrefix,
+			expectFound:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			h, ok := taskHandlers.lookup(tc.eventType, tc.subjectPrefix)
+
+			assert.Equal(t, tc.expectFound, ok)
+
+			if tc.expectFound {
+				assert.Equal(t, tc.expectHandler, h.name)
+			}
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestHandlerRegistryDispatch() { //nolint:govet
+	type testCase struct {
+		name          string
+		eventType     string
+		precondition  error
+		expectHandled bool
+	}
+
+	testCases := []testCase{
+		{
+			name:          "preconditions pass",
+			eventType:     "test",
+			expectHandled: true,
+		},
+		{
+			name:          "precondition fails",
+			eventType:     "test",
+			precondition:  errLoadBalancerTerminating,
+			expectHandled: false,
+		},
+		{
+			name:          "unknown event type",
+			eventType:     "certificate.rotated",
+			expectHandled: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			var handled bool
+
+			reg := newHandlerRegistry()
+			reg.register("test", anySubject, &taskHandler{
+				name:          "test",
+				preconditions: []precondition{func(*lbTask) error { return tc.precondition }},
+				handle: func(*lbTask) error {
+					handled = true
+					return nil
+				},
+			})
+
+			id := gidx.MustNewID(LBPrefix)
+			task := &lbTask{
+				lb:   &loadBalancer{loadBalancerID: id, lbType: typeLB},
+				ctx:  context.TODO(),
+				evt:  tc.eventType,
+				subj: id,
+				srv:  &Server{Logger: zap.NewNop().Sugar()},
+			}
+
+			reg.dispatch(task)
+
+			assert.Equal(t, tc.expectHandled, handled)
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestDispatchReleasesDedupeKey() { //nolint:govet
+	errHandler := errors.New("handler failed") //nolint:goerr113
+
+	type testCase struct {
+		name          string
+		handlerErr    error
+		expectClaimed bool
+	}
+
+	testCases := []testCase{
+		{name: "success keeps the key", expectClaimed: true},
+		{name: "failure releases the key", handlerErr: errHandler, expectClaimed: false},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			reg := newHandlerRegistry()
+			reg.register("test", anySubject, &taskHandler{
+				name:   "test",
+				handle: func(*lbTask) error { return tc.handlerErr },
+			})
+
+			dedupe := NewDedupeCache(10)
+			require.True(t, dedupe.claim("message"))
+
+			id := gidx.MustNewID(LBPrefix)
+			task := &lbTask{
+				lb:        &loadBalancer{loadBalancerID: id, lbType: typeLB},
+				ctx:       context.TODO(),
+				evt:       "test",
+				subj:      id,
+				srv:       &Server{Logger: zap.NewNop().Sugar(), Dedupe: dedupe},
+				dedupeKey: "message",
+			}
+
+			reg.dispatch(task)
+
+			assert.Equal(t, tc.expectClaimed, !dedupe.claim("message"))
+		})
+	}
+}
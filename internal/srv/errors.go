@@ -14,4 +14,20 @@ var (
 	errInvalidHelmValues       = errors.New("unable to create helm values")
 	errLoadBalancerInit        = errors.New("unable to initialize loadbalancer data")
 	errNotMyMessage            = errors.New("message not for this location")
+	errLoadBalancerTerminating = errors.New("loadbalancer is terminating")
+	errPhaseTimeout            = errors.New("operation timed out")
+	errKVUnsupported           = errors.New("events connection does not support nats key value buckets")
+	errInvalidGuardrails       = errors.New("unable to apply namespace guardrails")
+	errInvalidQuantity         = errors.New("invalid resource quantity")
+	errNamespaceStuck          = errors.New("namespace stuck terminating")
+	errInvalidClusterConfig    = errors.New("cluster requires a kubeconfig path or secret")
+	errEventsNotConnected      = errors.New("events connection is not connected")
+	errChartNotLoaded          = errors.New("loadbalancer chart is not loaded")
+	errMissingPermissions      = errors.New("missing kubernetes permissions")
+	errEndpointUnavailable     = errors.New("endpoint unavailable")
+	errAuditUnsupported        = errors.New("events connection does not support publishing audit records")
+	errNoHandler               = errors.New("no handler registered for event")
+	errInvalidReplayRecord     = errors.New("replay record must hold a change or an event message")
+	errInvalidFixture          = errors.New("invalid loadbalancer fixture")
+	errStatusUpdateDelayed     = errors.New("status update delayed by earlier updates that have not been delivered")
 )
@@ -0,0 +1,146 @@
+package srv
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyv1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applymetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	networkingapplyv1 "k8s.io/client-go/applyconfigurations/networking/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// guardrailName is the name of the quota, limit range and network policy in each loadbalancer namespace
+const guardrailName = "load-balancer-operator"
+
+// applyGuardrails server-side applies the configured ResourceQuota, LimitRange and
+// NetworkPolicy to the namespace of a loadbalancer
+func (s *Server) applyGuardrails(ctx context.Context, client kubernetes.Interface, namespace string, lb *loadBalancer) error {
+	opts := metav1.ApplyOptions{FieldManager: "loadbalanceroperator"}
+
+	quota, err := s.resourceQuota(namespace)
+	if err != nil {
+		return err
+	}
+
+	if quota != nil {
+		if _, err := client.CoreV1().ResourceQuotas(namespace).Apply(ctx, quota, opts); err != nil {
+			return fmt.Errorf("resource quota: %w", err)
+		}
+	}
+
+	limits, err := s.limitRange(namespace)
+	if err != nil {
+		return err
+	}
+
+	if limits != nil {
+		if _, err := client.CoreV1().LimitRanges(namespace).Apply(ctx, limits, opts); err != nil {
+			return fmt.Errorf("limit range: %w", err)
+		}
+	}
+
+	if s.Namespace.NetworkPolicy {
+		if _, err := client.NetworkingV1().NetworkPolicies(namespace).Apply(ctx, s.networkPolicy(namespace, lb), opts); err != nil {
+			return fmt.Errorf("network policy: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// resourceQuota returns the quota for the namespace or nil when no quota is configured
+func (s *Server) resourceQuota(namespace string) (*applyv1.ResourceQuotaApplyConfiguration, error) {
+	if len(s.Namespace.ResourceQuota) == 0 {
+		return nil, nil
+	}
+
+	hard, err := resourceList(s.Namespace.ResourceQuota)
+	if err != nil {
+		return nil, err
+	}
+
+	return applyv1.ResourceQuota(guardrailName, namespace).
+		WithSpec(applyv1.ResourceQuotaSpec().WithHard(hard)), nil
+}
+
+// limitRange returns the container limits for the namespace or nil when no limits are configured
+func (s *Server) limitRange(namespace string) (*applyv1.LimitRangeApplyConfiguration, error) {
+	cfg := s.Namespace.LimitRange
+	if len(cfg.Default) == 0 && len(cfg.DefaultRequest) == 0 && len(cfg.Max) == 0 {
+		return nil, nil
+	}
+
+	item := applyv1.LimitRangeItem().WithType(v1.LimitTypeContainer)
+
+	for _, l := range []struct {
+		values map[string]string
+		set    func(v1.ResourceList) *applyv1.LimitRangeItemApplyConfiguration
+	}{
+		{cfg.Default, item.WithDefault},
+		{cfg.DefaultRequest, item.WithDefaultRequest},
+		{cfg.Max, item.WithMax},
+	} {
+		if len(l.values) == 0 {
+			continue
+		}
+
+		list, err := resourceList(l.values)
+		if err != nil {
+			return nil, err
+		}
+
+		l.set(list)
+	}
+
+	return applyv1.LimitRange(guardrailName, namespace).
+		WithSpec(applyv1.LimitRangeSpec().WithLimits(item)), nil
+}
+
+// networkPolicy returns a policy that denies all ingress to the namespace except to the
+// loadbalancer ports and the metrics port. Egress is not restricted so that
+// loadbalancers can reach their origins. The load-balancer-api does not record the
+// protocol of a port, so every port is allowed for TCP only.
+func (s *Server) networkPolicy(namespace string, lb *loadBalancer) *networkingapplyv1.NetworkPolicyApplyConfiguration {
+	ports := []int{s.MetricsPort}
+
+	if lb != nil && lb.lbData != nil {
+		for _, port := range lb.lbData.Ports.Edges {
+			ports = append(ports, int(port.Node.Number))
+		}
+	}
+
+	rule := networkingapplyv1.NetworkPolicyIngressRule()
+
+	for _, port := range ports {
+		rule.WithPorts(networkingapplyv1.NetworkPolicyPort().
+			WithProtocol(v1.ProtocolTCP).
+			WithPort(intstr.FromInt(port)))
+	}
+
+	return networkingapplyv1.NetworkPolicy(guardrailName, namespace).
+		WithSpec(networkingapplyv1.NetworkPolicySpec().
+			WithPodSelector(applymetav1.LabelSelector()).
+			WithPolicyTypes(networkingv1.PolicyTypeIngress).
+			WithIngress(rule))
+}
+
+func resourceList(values map[string]string) (v1.ResourceList, error) {
+	list := make(v1.ResourceList, len(values))
+
+	for name, value := range values {
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s=%s: %w", errInvalidQuantity, name, value, err)
+		}
+
+		list[v1.ResourceName(name)] = q
+	}
+
+	return list, nil
+}
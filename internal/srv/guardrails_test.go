@@ -0,0 +1,88 @@
+package srv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/gidx"
+	v1 "k8s.io/api/core/v1"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+)
+
+func (suite *srvTestSuite) TestNetworkPolicy() { //nolint:govet
+	id := gidx.MustNewID(LBPrefix)
+
+	lb := &loadBalancer{
+		loadBalancerID: id,
+		lbData: &lbapi.LoadBalancer{
+			ID: id.String(),
+			Ports: lbapi.Ports{Edges: []lbapi.PortEdges{
+				{Node: lbapi.PortNode{Number: 80}},
+				{Node: lbapi.PortNode{Number: 443}},
+			}},
+		},
+	}
+
+	srv := Server{MetricsPort: 29782}
+
+	policy := srv.networkPolicy("lb-abc", lb)
+
+	assert.Equal(suite.T(), guardrailName, *policy.Name)
+	assert.Equal(suite.T(), "lb-abc", *policy.Namespace)
+	assert.Len(suite.T(), policy.Spec.Ingress, 1)
+
+	var ports []int
+
+	for _, p := range policy.Spec.Ingress[0].Ports {
+		assert.Equal(suite.T(), v1.ProtocolTCP, *p.Protocol)
+
+		ports = append(ports, p.Port.IntValue())
+	}
+
+	assert.ElementsMatch(suite.T(), []int{29782, 80, 443}, ports)
+}
+
+func (suite *srvTestSuite) TestResourceQuota() { //nolint:govet
+	type testCase struct {
+		name      string
+		quota     map[string]string
+		expectNil bool
+		expectErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:      "not configured",
+			expectNil: true,
+		},
+		{
+			name:  "valid quota",
+			quota: map[string]string{"pods": "10", "limits.memory": "1Gi"},
+		},
+		{
+			name:      "invalid quantity",
+			quota:     map[string]string{"pods": "lots"},
+			expectNil: true,
+			expectErr: errInvalidQuantity,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			srv := Server{Namespace: config.NamespaceConfig{ResourceQuota: tc.quota}}
+
+			quota, err := srv.resourceQuota("lb-abc")
+
+			assert.ErrorIs(t, err, tc.expectErr)
+
+			if tc.expectNil {
+				assert.Nil(t, quota)
+				return
+			}
+
+			assert.Len(t, *quota.Spec.Hard, len(tc.quota))
+		})
+	}
+}
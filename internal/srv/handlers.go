@@ -2,17 +2,18 @@ package srv
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
-	lbmeta "go.infratographer.com/load-balancer-api/pkg/metadata"
 	"go.infratographer.com/x/events"
 	"go.infratographer.com/x/gidx"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"golang.org/x/exp/slices"
-
-	"go.infratographer.com/load-balancer-operator/internal/config"
+	v1 "k8s.io/api/core/v1"
 )
 
 func (s *Server) locationCheck(i gidx.PrefixedID) bool {
@@ -32,34 +33,7 @@ func (s *Server) listenEvent(messages <-chan events.Message[events.EventMessage]
 }
 
 func (s *Server) processEvent(msg events.Message[events.EventMessage]) {
-	m := msg.Message()
-
-	ctx, span := otel.Tracer(instrumentationName).Start(m.GetTraceContext(s.Context), "processEvent")
-	defer span.End()
-
-	lb, err := prepareLoadBalancer[events.EventMessage](ctx, m, s)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-	}
-
-	if err == nil && lb != nil && lb.lbType != typeNoLB {
-		span.SetAttributes(
-			attribute.String("loadbalancer.id", lb.loadBalancerID.String()),
-			attribute.String("message.event", m.EventType),
-			attribute.String("message.id", msg.ID()),
-			attribute.String("message.subject", m.SubjectID.String()),
-		)
-
-		ch := s.checkChannel(ctx, lb)
-		ch.writer <- &lbTask{lb: lb, ctx: ctx, evt: m.EventType, srv: s}
-	}
-
-	// we need to Acknowledge that we received and processed the message,
-	// otherwise, it will be resent over and over again.
-	if err := msg.Ack(); err != nil {
-		s.Logger.Errorw("unable to acknowledge message", "error", err, "messageID", msg.ID())
-	}
+	processMessage(s, msg, msg.Message().Timestamp, "processEvent")
 }
 
 func (s *Server) listenChange(messages <-chan events.Message[events.ChangeMessage]) {
@@ -69,40 +43,132 @@ func (s *Server) listenChange(messages <-chan events.Message[events.ChangeMessag
 }
 
 func (s *Server) processChange(msg events.Message[events.ChangeMessage]) {
+	processMessage(s, msg, msg.Message().Timestamp, "processChange")
+}
+
+// processMessage hands a received message to the runner of its loadbalancer and acks it.
+// The dedupe key of the message is released when its task fails or cannot be queued, so
+// a later delivery of the message is processed again. Messages whose loadbalancer could not
+// be looked up are redelivered according to LookupRetry instead of being acked.
+func processMessage[M Message](s *Server, msg events.Message[M], ts time.Time, spanName string) {
 	m := msg.Message()
+	eventType := m.GetEventType()
+	subject := m.GetSubject()
 
-	ctx, span := otel.Tracer(instrumentationName).Start(m.GetTraceContext(s.Context), "processChange")
+	ctx, span := otel.Tracer(instrumentationName).Start(m.GetTraceContext(s.Context), spanName)
 	defer span.End()
 
-	lb, err := prepareLoadBalancer[events.ChangeMessage](ctx, m, s)
+	messagesReceivedCounter.WithLabelValues(msg.Topic(), eventType).Inc()
+
+	key := dedupeKey(msg, subject, eventType, ts)
+	if !s.Dedupe.claim(key) {
+		s.Logger.Debugw("skipping duplicate message", "messageID", msg.ID(), "subjectID", subject.String(), "event", eventType)
+		span.SetAttributes(attribute.Bool("message.duplicate", true))
+		duplicateMessagesCounter.Inc()
+		s.ackMessage(msg, eventType)
+
+		return
+	}
+
+	lb, err := prepareLoadBalancer(ctx, m, s)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
+
+		// allow a later delivery of this message to be processed
+		s.Dedupe.release(key)
+
+		if !errors.Is(err, errNotMyMessage) {
+			messagesFailedCounter.WithLabelValues(msg.Topic(), eventType).Inc()
+
+			if s.redeliver(msg, eventType, err) {
+				return
+			}
+		}
 	}
 
 	if err == nil && lb != nil && lb.lbType != typeNoLB {
 		span.SetAttributes(
 			attribute.String("loadbalancer.id", lb.loadBalancerID.String()),
-			attribute.String("message.event", m.EventType),
+			attribute.String("message.event", eventType),
 			attribute.String("message.id", msg.ID()),
-			attribute.String("message.subject", m.SubjectID.String()),
+			attribute.String("message.subject", subject.String()),
 		)
 
-		ch := s.checkChannel(ctx, lb)
-		ch.writer <- &lbTask{lb: lb, ctx: ctx, evt: m.EventType, srv: s}
+		ctx = withMessageID(ctx, messageID(msg))
+		ctx = withCluster(ctx, s.clusterFor(lb, m.GetAddSubjects()))
+
+		t := newTask(ctx, s, lb, eventType, subject)
+		t.dedupeKey = key
+
+		if !s.checkChannel(ctx, lb).submit(t) {
+			s.Logger.Debugw("loadbalancer runner stopped, dropping message", "loadbalancer", lb.loadBalancerID.String(), "messageID", msg.ID())
+			s.recordEvent(ctx, lb, v1.EventTypeNormal, eventReasonSkipped, "dropped %s event for %s: loadbalancer runner stopped", eventType, subject)
+			s.Dedupe.release(key)
+			t.cancelTask()
+		}
+	}
+
+	s.ackMessage(msg, eventType)
+}
+
+// redeliver naks a message that failed with a retryable error so that it is delivered
+// again after the lookup retry delay. It reports whether the message was nak'd.
+func (s *Server) redeliver(msg nakable, eventType string, err error) bool {
+	if !isRetryable(err) {
+		return false
+	}
+
+	delay, ok := redeliveryDelay(s.LookupRetry, msg.Deliveries())
+	if !ok {
+		s.Logger.Warnw("giving up on message", "error", err, "messageID", msg.ID(), "deliveries", msg.Deliveries())
+		return false
+	}
+
+	if nakErr := msg.Nak(delay); nakErr != nil {
+		s.Logger.Errorw("unable to nak message", "error", nakErr, "messageID", msg.ID())
+		return false
 	}
 
-	// we need to Acknowledge that we received and processed the message,
-	// otherwise, it will be resent over and over again.
+	s.Logger.Debugw("message will be redelivered", "error", err, "messageID", msg.ID(), "event", eventType, "retryIn", delay)
+
+	return true
+}
+
+// nakable is a received message of any type that can be redelivered
+type nakable interface {
+	Nak(time.Duration) error
+	Deliveries() uint64
+	ID() string
+}
+
+// ackable is a received message of any type that can be acknowledged
+type ackable interface {
+	Ack() error
+	ID() string
+	Topic() string
+}
+
+// ackMessage acknowledges that we received and processed the message,
+// otherwise, it will be resent over and over again.
+func (s *Server) ackMessage(msg ackable, eventType string) {
 	if err := msg.Ack(); err != nil {
 		s.Logger.Errorw("unable to acknowledge message", "error", err, "messageID", msg.ID())
+		messagesFailedCounter.WithLabelValues(msg.Topic(), eventType).Inc()
+
+		return
 	}
+
+	messagesAckedCounter.WithLabelValues(msg.Topic(), eventType).Inc()
 }
 
 func (s *Server) checkChannel(ctx context.Context, lb *loadBalancer) *runner {
 	ctx, span := otel.Tracer(instrumentationName).Start(ctx, "checkChannel")
 	defer span.End()
 
+	unlock := s.lockRegistry()
+	defer unlock()
+
 	ch, ok := s.LoadBalancers[lb.loadBalancerID.String()]
 	if !ok {
 		span.SetAttributes(attribute.Bool("channel-exists", false))
@@ -115,6 +181,8 @@ func (s *Server) checkChannel(ctx context.Context, lb *loadBalancer) *runner {
 		span.SetAttributes(attribute.Bool("channel-exists", true))
 	}
 
+	ch.track(lb)
+
 	return ch
 }
 
@@ -139,7 +207,7 @@ func prepareLoadBalancer[M Message](ctx context.Context, msg M, s *Server) (*loa
 			lb, err = s.newLoadBalancer(ctx, msg.GetSubject(), msg.GetAddSubjects())
 			if err != nil {
 				s.Logger.Errorw("unable to initialize loadbalancer", "error", err, "subjectID", msg.GetSubject().String())
-				err = errLoadBalancerInit
+				err = fmt.Errorf("%w: %w", errLoadBalancerInit, err)
 				span.RecordError(err)
 				span.SetStatus(codes.Error, err.Error())
 
@@ -163,85 +231,3 @@ func prepareLoadBalancer[M Message](ctx context.Context, msg M, s *Server) (*loa
 
 	return nil, errNotMyMessage
 }
-
-func process(t *lbTask) {
-	var (
-		status *lbmeta.LoadBalancerStatus
-		err    error
-	)
-
-	if t.evt != string(events.DeleteChangeType) {
-		status, err = lbmeta.GetLoadbalancerStatus(t.lb.lbData.Metadata.Statuses, config.AppConfig.Metadata.StatusNamespaceID, lbmeta.LoadBalancerAPISource)
-		if err != nil {
-			// note the loadbalancer state is off/amiss, continue processing event
-			t.srv.Logger.Warnw("failed to find loadbalancer state", "error", err, "loadbalancer", t.lb.loadBalancerID, "event", t.evt)
-		}
-	}
-
-	switch {
-	case t.evt == "ip-address.assigned":
-		t.srv.Logger.Debugw("ip address processed. updating loadbalancer", "loadbalancer", t.lb.loadBalancerID.String())
-
-		if status != nil && status.State == lbmeta.LoadBalancerStateTerminating {
-			t.srv.Logger.Infow("ignoring event", "loadbalancer", t.lb.loadBalancerID, "loadbalancerState", status.State, "event", t.evt)
-			return
-		}
-
-		if err := t.srv.createDeployment(t.ctx, t.lb); err != nil {
-			t.srv.Logger.Errorw("unable to update loadbalancer", "error", err, "loadbalancer", t.lb.loadBalancerID.String())
-		}
-
-		return
-	case t.evt == string(events.CreateChangeType) && t.lb.lbType == typeLB:
-		if status != nil && status.State == lbmeta.LoadBalancerStateTerminating {
-			t.srv.Logger.Infow("ignoring event", "loadbalancer", t.lb.loadBalancerID, "loadbalancerState", status.State, "event", t.evt)
-			return
-		}
-
-		t.srv.Logger.Debugw("creating loadbalancer", "loadbalancer", t.lb.loadBalancerID)
-
-		if err := t.srv.processLoadBalancerChangeCreate(t.ctx, t.lb); err != nil {
-			t.srv.Logger.Errorw("handler unable to create loadbalancer", "error", err, "loadbalancer", t.lb.loadBalancerID)
-		} else {
-			sts := &lbmeta.LoadBalancerStatus{State: lbmeta.LoadBalancerStateActive}
-			if err := t.srv.LoadBalancerStatusUpdate(t.ctx, t.lb.loadBalancerID, sts); err != nil {
-				t.srv.Logger.Errorw("failed to update metadata", "error", err, "loadbalancer", t.lb.loadBalancerID, "loadbalancerState", sts.State)
-			}
-		}
-
-		return
-	case t.evt == string(events.DeleteChangeType) && t.lb.lbType == typeLB:
-		t.srv.Logger.Debugw("deleting loadbalancer", "loadbalancer", t.lb.loadBalancerID)
-
-		if err := t.srv.processLoadBalancerChangeDelete(t.ctx, t.lb); err != nil {
-			t.srv.Logger.Errorw("handler unable to delete loadbalancer", "error", err, "loadbalancer", t.lb.loadBalancerID)
-		}
-
-		sts := &lbmeta.LoadBalancerStatus{State: lbmeta.LoadBalancerStateDeleted}
-		if err := t.srv.LoadBalancerStatusUpdate(t.ctx, t.lb.loadBalancerID, sts); err != nil {
-			t.srv.Logger.Errorw("failed to update metadata", "error", err, "loadbalancer", t.lb.loadBalancerID, "loadbalancerState", sts.State)
-		}
-
-		ch, ok := t.srv.LoadBalancers[t.lb.loadBalancerID.String()]
-		if ok {
-			ch.stop()
-		}
-
-		delete(t.srv.LoadBalancers, t.lb.loadBalancerID.String())
-
-		return
-	case t.evt == "ip-address.unassigned":
-		t.srv.Logger.Debugw("ip address unassigned. updating loadbalancer", "loadbalancer", t.lb.loadBalancerID.String())
-	default:
-		t.srv.Logger.Debugw("updating loadbalancer", "loadbalancer", t.lb.loadBalancerID.String())
-
-		if status != nil && status.State == lbmeta.LoadBalancerStateTerminating {
-			t.srv.Logger.Infow("ignoring event", "loadbalancer", t.lb.loadBalancerID, "loadbalancerState", status.State, "event", t.evt)
-			return
-		}
-
-		if err := t.srv.processLoadBalancerChangeUpdate(t.ctx, t.lb); err != nil {
-			t.srv.Logger.Errorw("handler unable to update loadbalancer", "error", err, "loadbalancerID", t.lb.loadBalancerID.String())
-		}
-	}
-}
@@ -77,7 +77,7 @@ func (suite *srvTestSuite) TestProcessChange() { //nolint:govet
 
 	srv := Server{
 		APIClient:     lbapi.NewClient(api.URL),
-		BackoffConfig: backoffPolicy,
+		RetryPolicies: map[Operation]backoff.Policy{OpUpgrade: backoffPolicy},
 		Echo:          eSrv,
 		Context:       context.TODO(),
 		Logger:        zap.NewNop().Sugar(),
@@ -189,7 +189,7 @@ func (suite *srvTestSuite) TestProcessEvent() { //nolint:govet
 
 	srv := Server{
 		APIClient:        lbapi.NewClient(api.URL),
-		BackoffConfig:    backoffPolicy,
+		RetryPolicies:    map[Operation]backoff.Policy{OpUpgrade: backoffPolicy},
 		Echo:             eSrv,
 		Context:          context.TODO(),
 		Logger:           zap.NewNop().Sugar(),
@@ -15,4 +15,10 @@ func (s *Server) versionHandler(c echo.Context) error {
 
 func (s *Server) Routes(g *echo.Group) {
 	g.GET("/version", s.versionHandler)
+
+	s.adminRoutes(g.Group("/admin"))
+
+	if s.DevPublisher != nil {
+		s.devRoutes(g.Group("/dev"))
+	}
 }
@@ -1,6 +1,7 @@
 package srv
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -10,6 +11,7 @@ import (
 	"strconv"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/cli/values"
@@ -24,6 +26,7 @@ type helmvalues struct {
 
 const (
 	managedHelmKeyPrefix = "operator.managed"
+	dataPlaneCredsKey    = "dataPlaneAPICreds"
 )
 
 func (v helmvalues) generateLBHelmVals(lb *loadBalancer, s *Server) {
@@ -38,7 +41,7 @@ func (v helmvalues) generateLBHelmVals(lb *loadBalancer, s *Server) {
 	}
 
 	//  add dataplane api secret
-	v.StringValues = append(v.StringValues, fmt.Sprintf("%s=%s", managedHelmKeyPrefix+".dataPlaneAPICreds", generateSecret()))
+	v.StringValues = append(v.StringValues, fmt.Sprintf("%s=%s", managedHelmKeyPrefix+"."+dataPlaneCredsKey, generateSecret()))
 
 	// add port values
 	var cport, sport []interface{}
@@ -77,10 +80,11 @@ func (v helmvalues) generateLBHelmVals(lb *loadBalancer, s *Server) {
 // 	v.StringValues = append(v.StringValues, val)
 // }
 
-func (s *Server) newHelmClient(namespace string) (*action.Configuration, error) {
+func (s *Server) newHelmClient(ctx context.Context, namespace string) (*action.Configuration, error) {
 	config := &action.Configuration{}
 	cliopt := genericclioptions.NewConfigFlags(false)
-	wrapper := func(*rest.Config) *rest.Config { return s.KubeClient }
+	kubeConfig := s.restConfig(ctx)
+	wrapper := func(*rest.Config) *rest.Config { return kubeConfig }
 	cliopt.WithWrapConfigFn(wrapper)
 
 	err := config.Init(cliopt, namespace, "secret", s.Logger.Debugf)
@@ -126,3 +130,15 @@ func generateSecret() string {
 
 	return base64.StdEncoding.EncodeToString([]byte(string(r)))
 }
+
+// runHelmAction runs a helm action against a loadbalancer release in a child span of ctx
+// and records its duration
+func runHelmAction(ctx context.Context, op Operation, namespace, releaseName string, fn func(context.Context) error) error {
+	return withSpan(ctx, "helm."+string(op), func(ctx context.Context) error {
+		start := time.Now()
+		err := fn(ctx)
+		helmDuration.WithLabelValues(string(op), resultLabel(err)).Observe(time.Since(start).Seconds())
+
+		return err
+	}, attribute.String("helm.namespace", namespace), attribute.String("helm.release", releaseName))
+}
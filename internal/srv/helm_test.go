@@ -172,7 +172,7 @@ func (suite *srvTestSuite) TestNewHelmClient() {
 				LoadBalancers: make(map[string]*runner),
 			}
 
-			_, err := srv.newHelmClient(tcase.appNamespace)
+			_, err := srv.newHelmClient(context.TODO(), tcase.appNamespace)
 
 			if tcase.expectError {
 				assert.NotNil(t, err)
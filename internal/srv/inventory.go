@@ -0,0 +1,356 @@
+package srv
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"helm.sh/helm/v3/pkg/action"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultInventoryInterval is how often the managed loadbalancers are resynced
+	DefaultInventoryInterval = time.Minute
+
+	// releaseStatusNone is reported for loadbalancers that do not have a helm release
+	releaseStatusNone = "none"
+)
+
+// managedLoadBalancer is a loadbalancer the operator has deployed or is working on
+type managedLoadBalancer struct {
+	ID              string `json:"id"`
+	Cluster         string `json:"cluster"`
+	Namespace       string `json:"namespace,omitempty"`
+	Release         string `json:"release,omitempty"`
+	Location        string `json:"location,omitempty"`
+	ChartVersion    string `json:"chartVersion,omitempty"`
+	ReleaseStatus   string `json:"releaseStatus"`
+	ReleaseRevision int    `json:"releaseRevision,omitempty"`
+	// Stale is set when the cluster could not be searched by the last resync and the
+	// loadbalancer is reported as found by an earlier one
+	Stale bool `json:"stale,omitempty"`
+}
+
+// clusterInventoryStatus describes the last resync of a cluster
+type clusterInventoryStatus struct {
+	Cluster     string     `json:"cluster"`
+	LastAttempt time.Time  `json:"lastAttempt"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+// lbInventory holds the managed loadbalancers found by the last resync
+type lbInventory struct {
+	mu       sync.Mutex
+	lbs      []managedLoadBalancer
+	clusters map[string]clusterInventoryStatus
+	updated  time.Time
+}
+
+func newLBInventory() *lbInventory {
+	return &lbInventory{
+		clusters: make(map[string]clusterInventoryStatus),
+	}
+}
+
+// update records a resync that searched the provided clusters. The loadbalancers of
+// clusters that failed are kept from the previous resync and marked stale.
+func (i *lbInventory) update(lbs []managedLoadBalancer, clusters map[string]error) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	for _, m := range i.lbs {
+		if err, ok := clusters[m.Cluster]; ok && err != nil {
+			m.Stale = true
+			lbs = append(lbs, m)
+		}
+	}
+
+	sort.Slice(lbs, func(a, b int) bool { return lbs[a].ID < lbs[b].ID })
+
+	for name, err := range clusters {
+		status := i.clusters[name]
+		status.Cluster = name
+		status.LastAttempt = now
+		status.LastError = ""
+
+		if err != nil {
+			status.LastError = err.Error()
+		} else {
+			status.LastSuccess = &now
+		}
+
+		i.clusters[name] = status
+	}
+
+	i.lbs = lbs
+	i.updated = now
+}
+
+// clusterStatuses returns the resync status of each cluster ordered by name
+func (i *lbInventory) clusterStatuses() []clusterInventoryStatus {
+	if i == nil {
+		return []clusterInventoryStatus{}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	out := make([]clusterInventoryStatus, 0, len(i.clusters))
+	for _, status := range i.clusters {
+		out = append(out, status)
+	}
+
+	sort.Slice(out, func(a, b int) bool { return out[a].Cluster < out[b].Cluster })
+
+	return out
+}
+
+// list returns the managed loadbalancers ordered by ID
+func (i *lbInventory) list() []managedLoadBalancer {
+	if i == nil {
+		return []managedLoadBalancer{}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return append([]managedLoadBalancer{}, i.lbs...)
+}
+
+// takeInventory finds the loadbalancers managed by the operator. Every cluster is searched
+// for managed namespaces and the helm release in each namespace; loadbalancers with a
+// runner but no namespace yet are included without a release. The result of searching
+// each cluster is returned by cluster name, and a cluster that cannot be searched does
+// not prevent the others from being reported.
+func (s *Server) takeInventory(ctx context.Context) ([]managedLoadBalancer, map[string]error) {
+	tracked := s.trackedLoadBalancers()
+	lbs := make([]managedLoadBalancer, 0, len(tracked))
+
+	targets := append([]*Cluster{nil}, s.Clusters...)
+	clusters := make(map[string]error, len(targets))
+
+	for _, c := range targets {
+		cctx := withCluster(ctx, c)
+
+		found, err := s.clusterInventory(cctx)
+		clusters[clusterName(cctx)] = err
+
+		if err != nil {
+			continue
+		}
+
+		for _, m := range found {
+			if lb, ok := tracked[m.ID]; ok {
+				if m.Location == "" && lb.lbData != nil {
+					m.Location = lb.lbData.Location.ID
+				}
+
+				delete(tracked, m.ID)
+			}
+
+			lbs = append(lbs, m)
+		}
+	}
+
+	for id, lb := range tracked {
+		m := managedLoadBalancer{
+			ID:            id,
+			Cluster:       clusterName(withCluster(ctx, s.clusterFor(lb, nil))),
+			ReleaseStatus: releaseStatusNone,
+		}
+
+		// the loadbalancer may be deployed to a cluster that could not be searched
+		if err := clusters[m.Cluster]; err != nil {
+			continue
+		}
+
+		if lb.lbData != nil {
+			m.Location = lb.lbData.Location.ID
+		}
+
+		lbs = append(lbs, m)
+	}
+
+	sort.Slice(lbs, func(i, j int) bool { return lbs[i].ID < lbs[j].ID })
+
+	return lbs, clusters
+}
+
+// clusterInventory returns the loadbalancers deployed to the cluster targeted by the context
+func (s *Server) clusterInventory(ctx context.Context) ([]managedLoadBalancer, error) {
+	kc, err := kubernetes.NewForConfig(s.restConfig(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := kc.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: managedLabel + "=true"})
+	if err != nil {
+		return nil, err
+	}
+
+	lbs := make([]managedLoadBalancer, 0, len(namespaces.Items))
+
+	for _, ns := range namespaces.Items {
+		m := managedLoadBalancer{
+			ID:            ns.Annotations[lbIDAnnotation],
+			Cluster:       clusterName(ctx),
+			Namespace:     ns.Name,
+			Location:      ns.Labels[locationIDLabel],
+			ReleaseStatus: releaseStatusNone,
+		}
+
+		if m.ID == "" {
+			m.ID = ns.Name
+		}
+
+		if err := s.releaseInventory(ctx, &m); err != nil {
+			s.Logger.Debugw("unable to list loadbalancer releases", "error", err, "namespace", ns.Name, "cluster", m.Cluster)
+		}
+
+		lbs = append(lbs, m)
+	}
+
+	return lbs, nil
+}
+
+// releaseInventory sets the release details of a managed loadbalancer from the latest
+// helm release in its namespace
+func (s *Server) releaseInventory(ctx context.Context, m *managedLoadBalancer) error {
+	client, err := s.newHelmClient(ctx, m.Namespace)
+	if err != nil {
+		return err
+	}
+
+	list := action.NewList(client)
+	list.All = true
+	list.SetStateMask()
+
+	releases, err := list.Run()
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range releases {
+		if rel.Version < m.ReleaseRevision {
+			continue
+		}
+
+		m.Release = rel.Name
+		m.ReleaseRevision = rel.Version
+
+		if rel.Info != nil {
+			m.ReleaseStatus = rel.Info.Status.String()
+		}
+
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			m.ChartVersion = rel.Chart.Metadata.Version
+		}
+	}
+
+	return nil
+}
+
+// runInventory resyncs the managed loadbalancers every interval until ctx is canceled
+func (s *Server) runInventory(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		lbs, clusters := s.takeInventory(ctx)
+
+		for name, err := range clusters {
+			if err != nil {
+				s.Logger.Warnw("unable to resync managed loadbalancers", "error", err, "cluster", name)
+			}
+		}
+
+		s.inventory.update(lbs, clusters)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// inventoryCollector reports the number of managed loadbalancers from the last resync and
+// whether each cluster could be searched
+type inventoryCollector struct {
+	inventory   *lbInventory
+	desc        *prometheus.Desc
+	failedDesc  *prometheus.Desc
+	successDesc *prometheus.Desc
+}
+
+func newInventoryCollector(inventory *lbInventory) *inventoryCollector {
+	return &inventoryCollector{
+		inventory: inventory,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName("", subsystem, "managed_load_balancers"),
+			"Number of load balancers managed by the operator by cluster, location, chart version and release status",
+			[]string{"cluster", "location", "chart_version", "release_status"},
+			nil,
+		),
+		failedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("", subsystem, "inventory_resync_failed"),
+			"Whether the last resync of managed load balancers failed for a cluster",
+			[]string{"cluster"},
+			nil,
+		),
+		successDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("", subsystem, "inventory_last_success_timestamp_seconds"),
+			"Time of the last successful resync of managed load balancers for a cluster",
+			[]string{"cluster"},
+			nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *inventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+	ch <- c.failedDesc
+	ch <- c.successDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *inventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	type key struct {
+		cluster, location, chartVersion, releaseStatus string
+	}
+
+	counts := make(map[key]int)
+
+	for _, m := range c.inventory.list() {
+		counts[key{m.Cluster, m.Location, m.ChartVersion, m.ReleaseStatus}]++
+	}
+
+	for k, n := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), k.cluster, k.location, k.chartVersion, k.releaseStatus)
+	}
+
+	for _, status := range c.inventory.clusterStatuses() {
+		failed := 0.0
+		if status.LastError != "" {
+			failed = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.failedDesc, prometheus.GaugeValue, failed, status.Cluster)
+
+		if status.LastSuccess != nil {
+			ch <- prometheus.MustNewConstMetric(c.successDesc, prometheus.GaugeValue, float64(status.LastSuccess.Unix()), status.Cluster)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/gidx"
+)
+
+func (suite *srvTestSuite) TestInventoryCollector() { //nolint:govet
+	inventory := newLBInventory()
+	inventory.update([]managedLoadBalancer{
+		{ID: "loadbal-a", Cluster: "default", Location: "lctnloc-one", ChartVersion: "1.0.0", ReleaseStatus: "deployed"},
+		{ID: "loadbal-b", Cluster: "default", Location: "lctnloc-one", ChartVersion: "1.0.0", ReleaseStatus: "deployed"},
+		{ID: "loadbal-c", Cluster: "default", Location: "lctnloc-one", ChartVersion: "1.0.0", ReleaseStatus: "failed"},
+		{ID: "loadbal-d", Cluster: "east", Location: "lctnloc-two", ReleaseStatus: releaseStatusNone},
+	}, map[string]error{"default": nil, "east": nil})
+
+	expected := `
+# HELP load_balancer_operator_managed_load_balancers Number of load balancers managed by the operator by cluster, location, chart version and release status
+# TYPE load_balancer_operator_managed_load_balancers gauge
+load_balancer_operator_managed_load_balancers{chart_version="",cluster="east",location="lctnloc-two",release_status="none"} 1
+load_balancer_operator_managed_load_balancers{chart_version="1.0.0",cluster="default",location="lctnloc-one",release_status="deployed"} 2
+load_balancer_operator_managed_load_balancers{chart_version="1.0.0",cluster="default",location="lctnloc-one",release_status="failed"} 1
+`
+
+	require.NoError(suite.T(), testutil.CollectAndCompare(newInventoryCollector(inventory), strings.NewReader(expected), "load_balancer_operator_managed_load_balancers"))
+}
+
+func (suite *srvTestSuite) TestInventoryUpdate() { //nolint:govet
+	errUnreachable := errors.New("cluster unreachable") //nolint:goerr113
+
+	inventory := newLBInventory()
+	inventory.update([]managedLoadBalancer{
+		{ID: "loadbal-a", Cluster: "default"},
+		{ID: "loadbal-b", Cluster: "east"},
+	}, map[string]error{"default": nil, "east": nil})
+
+	// east cannot be searched, its loadbalancers are kept from the previous resync
+	inventory.update([]managedLoadBalancer{
+		{ID: "loadbal-c", Cluster: "default"},
+	}, map[string]error{"default": nil, "east": errUnreachable})
+
+	assert.Equal(suite.T(), []managedLoadBalancer{
+		{ID: "loadbal-b", Cluster: "east", Stale: true},
+		{ID: "loadbal-c", Cluster: "default"},
+	}, inventory.list())
+
+	clusters := inventory.clusterStatuses()
+	require.Len(suite.T(), clusters, 2)
+	assert.Equal(suite.T(), "default", clusters[0].Cluster)
+	assert.Empty(suite.T(), clusters[0].LastError)
+	assert.Equal(suite.T(), "east", clusters[1].Cluster)
+	assert.Equal(suite.T(), errUnreachable.Error(), clusters[1].LastError)
+	assert.NotNil(suite.T(), clusters[1].LastSuccess, "the last successful resync should be kept")
+
+	expected := `
+# HELP load_balancer_operator_inventory_resync_failed Whether the last resync of managed load balancers failed for a cluster
+# TYPE load_balancer_operator_inventory_resync_failed gauge
+load_balancer_operator_inventory_resync_failed{cluster="default"} 0
+load_balancer_operator_inventory_resync_failed{cluster="east"} 1
+`
+
+	require.NoError(suite.T(), testutil.CollectAndCompare(newInventoryCollector(inventory), strings.NewReader(expected), "load_balancer_operator_inventory_resync_failed"))
+}
+
+func (suite *srvTestSuite) TestTrackedLoadBalancers() { //nolint:govet
+	id := gidx.MustNewID(LBPrefix)
+	srv := Server{LoadBalancers: make(map[string]*runner)}
+
+	r := NewRunner(context.TODO(), func(*lbTask) {})
+	defer r.stop()
+
+	srv.LoadBalancers[id.String()] = r
+
+	withData := &loadBalancer{loadBalancerID: id, lbData: &lbapi.LoadBalancer{Location: lbapi.LocationNode{ID: "lctnloc-one"}}}
+
+	r.track(withData)
+	// deletes do not carry loadbalancer data
+	r.track(&loadBalancer{loadBalancerID: id})
+
+	tracked := srv.trackedLoadBalancers()
+	require.Contains(suite.T(), tracked, id.String())
+	assert.Equal(suite.T(), withData, tracked[id.String()])
+
+	assert.Equal(suite.T(), r, srv.removeRunner(id.String()))
+	assert.Nil(suite.T(), srv.removeRunner(id.String()))
+	assert.Empty(suite.T(), srv.trackedLoadBalancers())
+}
@@ -3,6 +3,7 @@ package srv
 import (
 	"context"
 
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
 	"go.infratographer.com/x/gidx"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/codes"
@@ -16,7 +17,16 @@ func (s *Server) newLoadBalancer(ctx context.Context, subj gidx.PrefixedID, adds
 	defer span.End()
 
 	if l.lbType != typeNoLB {
-		data, err := s.APIClient.GetLoadBalancer(ctx, l.loadBalancerID.String())
+		var data *lbapi.LoadBalancer
+
+		// the lookup is attempted once; failed lookups are retried by redelivering the message
+		err := s.withPhaseTimeout(ctx, phaseAPILookup, func(ctx context.Context) error {
+			var err error
+
+			data, err = s.APIClient.GetLoadBalancer(ctx, l.loadBalancerID.String())
+
+			return err
+		})
 		if err != nil {
 			s.Logger.Debugw("unable to get loadbalancer from API", "error", err, "loadBalancer", l.loadBalancerID.String())
 			span.RecordError(err)
@@ -0,0 +1,188 @@
+package srv
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogLevels holds the level of the operator logs. Debug logging can be enabled for single
+// loadbalancers and locations at runtime without enabling it for everything else.
+type LogLevels struct {
+	level zap.AtomicLevel
+
+	mu            sync.RWMutex
+	loadBalancers map[string]struct{}
+	locations     map[string]struct{}
+}
+
+// NewLeveledLogger returns logger limited to the info level, or the debug level when debug
+// is set, along with the LogLevels that lift the limit for the logs of single loadbalancers.
+// logger must be built at the debug level for the limit to be lifted.
+func NewLeveledLogger(logger *zap.SugaredLogger, debug bool) (*zap.SugaredLogger, *LogLevels) {
+	levels := &LogLevels{
+		level:         zap.NewAtomicLevelAt(zap.InfoLevel),
+		loadBalancers: make(map[string]struct{}),
+		locations:     make(map[string]struct{}),
+	}
+
+	if debug {
+		levels.level.SetLevel(zap.DebugLevel)
+	}
+
+	logger = logger.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &levelCore{Core: c, enabler: levels.level}
+	}))
+
+	return logger, levels
+}
+
+// setLoadBalancerDebug enables or disables debug logging for a loadbalancer
+func (l *LogLevels) setLoadBalancerDebug(id string, debug bool) {
+	l.setDebug(l.loadBalancers, id, debug)
+}
+
+// setLocationDebug enables or disables debug logging for the loadbalancers in a location
+func (l *LogLevels) setLocationDebug(id string, debug bool) {
+	l.setDebug(l.locations, id, debug)
+}
+
+func (l *LogLevels) setDebug(ids map[string]struct{}, id string, debug bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if debug {
+		ids[id] = struct{}{}
+	} else {
+		delete(ids, id)
+	}
+}
+
+// debugEnabled reports whether debug logging is enabled for the loadbalancer or its location
+func (l *LogLevels) debugEnabled(lbID, location string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if _, ok := l.loadBalancers[lbID]; ok {
+		return true
+	}
+
+	_, ok := l.locations[location]
+
+	return ok
+}
+
+// logLevelSummary describes the log level and the debug logging enabled at runtime
+type logLevelSummary struct {
+	Level         string   `json:"level"`
+	LoadBalancers []string `json:"loadBalancers"`
+	Locations     []string `json:"locations"`
+}
+
+func (l *LogLevels) summary() logLevelSummary {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return logLevelSummary{
+		Level:         l.level.String(),
+		LoadBalancers: sortedKeys(l.loadBalancers),
+		Locations:     sortedKeys(l.locations),
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// levelCore limits the entries written by a core to the levels of its enabler. The core
+// it wraps decides on the levels it supports on its own.
+type levelCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabler.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
+
+// lbLevelEnabler enables debug logging when it is enabled for a loadbalancer at runtime
+type lbLevelEnabler struct {
+	levels   *LogLevels
+	lbID     string
+	location string
+}
+
+func (e lbLevelEnabler) Enabled(lvl zapcore.Level) bool {
+	return e.levels.level.Enabled(lvl) || e.levels.debugEnabled(e.lbID, e.location)
+}
+
+type loggerKey struct{}
+
+// withLogger returns a context carrying the logger of the work in progress
+func withLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// logger returns the logger of the work in progress, or the server logger
+func (s *Server) logger(ctx context.Context) *zap.SugaredLogger {
+	if l, ok := ctx.Value(loggerKey{}).(*zap.SugaredLogger); ok {
+		return l
+	}
+
+	return s.Logger
+}
+
+// taskLogger returns a logger for a task on a loadbalancer. Entries carry the loadbalancer,
+// message, event and trace of the task and are written at the debug level when debug
+// logging is enabled for the loadbalancer or its location.
+func (s *Server) taskLogger(ctx context.Context, lb *loadBalancer, evt string) *zap.SugaredLogger {
+	logger := s.Logger.With("loadBalancer", lb.loadBalancerID.String(), "event", evt)
+
+	if id := messageIDFromContext(ctx); id != "" {
+		logger = logger.With("messageID", id)
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		logger = logger.With("traceID", sc.TraceID().String())
+	}
+
+	if s.LogLevels == nil {
+		return logger
+	}
+
+	enabler := lbLevelEnabler{levels: s.LogLevels, lbID: lb.loadBalancerID.String()}
+	if lb.lbData != nil {
+		enabler.location = lb.lbData.Location.ID
+	}
+
+	return logger.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		if lc, ok := c.(*levelCore); ok {
+			return &levelCore{Core: lc.Core, enabler: enabler}
+		}
+
+		return c
+	}))
+}
@@ -0,0 +1,73 @@
+package srv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func (suite *srvTestSuite) TestTaskLogger() { //nolint:govet
+	type testCase struct {
+		name      string
+		debugLB   bool
+		debugLctn bool
+		expectLvl []zapcore.Level
+	}
+
+	id := gidx.MustNewID(LBPrefix)
+	location := "lctnloc-test"
+
+	testCases := []testCase{
+		{
+			name:      "info",
+			expectLvl: []zapcore.Level{zapcore.InfoLevel},
+		},
+		{
+			name:      "loadbalancer debug",
+			debugLB:   true,
+			expectLvl: []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel},
+		},
+		{
+			name:      "location debug",
+			debugLctn: true,
+			expectLvl: []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel},
+		},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			logger, levels := NewLeveledLogger(zap.New(core).Sugar(), false)
+
+			srv := Server{Logger: logger, LogLevels: levels}
+			lb := &loadBalancer{loadBalancerID: id, lbType: typeLB, lbData: &lbapi.LoadBalancer{}}
+			lb.lbData.Location.ID = location
+
+			levels.setLoadBalancerDebug(id.String(), tcase.debugLB)
+			levels.setLocationDebug(location, tcase.debugLctn)
+
+			task := newTask(withMessageID(context.TODO(), "stream/42"), &srv, lb, "create", id)
+			defer task.cancelTask()
+
+			task.logger().Debug("debug")
+			task.logger().Info("info")
+			srv.Logger.Debug("other loadbalancers are not logged at debug")
+
+			entries := logs.All()
+			assert.Len(t, entries, len(tcase.expectLvl))
+
+			for i, entry := range entries {
+				assert.Equal(t, tcase.expectLvl[i], entry.Level)
+				assert.Equal(t, id.String(), entry.ContextMap()["loadBalancer"])
+				assert.Equal(t, "stream/42", entry.ContextMap()["messageID"])
+				assert.Equal(t, "create", entry.ContextMap()["event"])
+			}
+		})
+	}
+}
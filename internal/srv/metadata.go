@@ -7,22 +7,54 @@ import (
 
 	"go.infratographer.com/x/events"
 	"go.infratographer.com/x/gidx"
+	"go.opentelemetry.io/otel/attribute"
 
-	metastatus "go.infratographer.com/load-balancer-api/pkg/metadata"
 	metacli "go.infratographer.com/metadata-api/pkg/client"
 
 	"go.infratographer.com/load-balancer-operator/internal/config"
 )
 
-// LoadBalancerStatusUpdate updates the state of a load balancer in the metadata service
-func (s Server) LoadBalancerStatusUpdate(ctx context.Context, loadBalancerID gidx.PrefixedID, status *metastatus.LoadBalancerStatus) error {
-	// publish event even if metadata endpoint is not configured
+// LoadBalancerStatusUpdate updates the state of a load balancer in the metadata service.
+// When an Outbox is configured the update is stored and delivered once, unless earlier
+// updates of the load balancer are backing off. Updates that cannot be delivered are
+// retried in the background, the error of the attempt, or errStatusUpdateDelayed when no
+// attempt was made, is still returned so callers know the update has not been delivered.
+func (s *Server) LoadBalancerStatusUpdate(ctx context.Context, loadBalancerID gidx.PrefixedID, status *LoadBalancerStatus) error {
+	if s.Outbox != nil {
+		_, err := s.Outbox.add(loadBalancerID, status)
+		if err == nil {
+			if !s.Outbox.isDue(loadBalancerID, time.Now()) {
+				return errStatusUpdateDelayed
+			}
+
+			return s.flushOutbox(ctx, loadBalancerID)
+		}
+
+		s.logger(ctx).Warnw("unable to store status update in outbox", "error", err, "loadBalancer", loadBalancerID.String())
+	}
+
+	// publish event even if metadata endpoint is not configured; only the write is retried
+	// so the event is not republished on every attempt
 	if err := s.publishLoadBalancerMetadata(ctx, loadBalancerID, status); err != nil {
-		s.Logger.Warnf("Failed to publish event: %w", err)
+		s.logger(ctx).Warnw("failed to publish event", "error", err)
+	}
+
+	err := s.retry(ctx, OpMetadataUpdate, func(ctx context.Context) error {
+		return s.withPhaseTimeout(ctx, phaseMetadata, func(ctx context.Context) error {
+			return s.metadataStatusUpdate(ctx, loadBalancerID, status)
+		})
+	})
+	if err != nil {
+		statusUpdateFailuresCounter.WithLabelValues(string(status.State)).Inc()
 	}
 
+	return err
+}
+
+// metadataStatusUpdate writes the status to the metadata service when it is configured
+func (s *Server) metadataStatusUpdate(ctx context.Context, loadBalancerID gidx.PrefixedID, status *LoadBalancerStatus) error {
 	if config.AppConfig.Metadata.Endpoint == "" {
-		s.Logger.Warnln("metadata not configured")
+		s.logger(ctx).Warnln("metadata not configured")
 		return nil
 	}
 
@@ -31,44 +63,72 @@ func (s Server) LoadBalancerStatusUpdate(ctx context.Context, loadBalancerID gid
 		return err
 	}
 
-	if _, err := s.MetadataClient.StatusUpdate(ctx, &metacli.StatusUpdateInput{
-		NodeID:      loadBalancerID.String(),
-		NamespaceID: config.AppConfig.Metadata.StatusNamespaceID.String(),
-		Source:      config.AppConfig.Metadata.Source,
-		Data:        json.RawMessage(jsonBytes),
-	}); err != nil {
+	err = withSpan(ctx, "metadata.statusUpdate", func(ctx context.Context) error {
+		_, err := s.MetadataClient.StatusUpdate(ctx, &metacli.StatusUpdateInput{
+			NodeID:      loadBalancerID.String(),
+			NamespaceID: config.AppConfig.Metadata.StatusNamespaceID.String(),
+			Source:      config.AppConfig.Metadata.Source,
+			Data:        json.RawMessage(jsonBytes),
+		})
+
 		return err
-	}
+	}, attribute.String("loadbalancer.id", loadBalancerID.String()), attribute.String("loadbalancer.state", string(status.State)))
+	s.audit(ctx, auditRecord{
+		Action:          auditMetadataWrite,
+		LoadBalancerID:  loadBalancerID.String(),
+		ValuesHashAfter: valuesHash(json.RawMessage(jsonBytes)),
+	}, err)
 
-	return nil
+	return err
 }
 
-func (s Server) publishLoadBalancerMetadata(ctx context.Context, loadBalancerID gidx.PrefixedID, status *metastatus.LoadBalancerStatus) error {
+// publishLoadBalancerMetadata publishes the status on the load-balancer.<state> subject.
+// The event data carries the same document that is written to the metadata service.
+func (s *Server) publishLoadBalancerMetadata(ctx context.Context, loadBalancerID gidx.PrefixedID, status *LoadBalancerStatus) error {
+	// messages are replayed without an events connection
+	if s.EventsConnection == nil {
+		return nil
+	}
+
 	eventType := "metadata"
 
-	subject := "load-balancer"
+	subject := "load-balancer." + string(status.State)
 
-	switch status.State {
-	case metastatus.LoadBalancerStateDeleted:
-		subject += ".deleted"
-	case metastatus.LoadBalancerStateActive:
-		subject += ".active"
-	default:
-		s.Logger.Debugf("skipping publish message for status: %s", string(status.State))
-		return nil
+	data, err := statusData(status)
+	if err != nil {
+		return err
+	}
+
+	timestamp := status.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now().UTC()
 	}
 
 	msg := events.EventMessage{
 		EventType: eventType,
 		SubjectID: loadBalancerID,
 		Source:    config.AppConfig.Metadata.Source,
-		Timestamp: time.Now().UTC(),
+		Timestamp: timestamp,
+		Data:      data,
 	}
 
 	// full topic = cfg.PublisherPrefix + "events" + eventType + subject
-	if _, err := s.EventsConnection.PublishEvent(ctx, subject, msg); err != nil {
+	return withSpan(ctx, "events.publish", func(ctx context.Context) error {
+		_, err := s.EventsConnection.PublishEvent(ctx, subject, msg)
 		return err
+	}, attribute.String("loadbalancer.id", loadBalancerID.String()), attribute.String("events.subject", subject))
+}
+
+func statusData(status *LoadBalancerStatus) (map[string]interface{}, error) {
+	b, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return data, nil
 }
@@ -0,0 +1,91 @@
+package srv
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	ownerIDLabel    = "com.infratographer.lb-operator/owner-id"
+	locationIDLabel = "com.infratographer.lb-operator/location-id"
+	lbNameLabel     = "com.infratographer.lb-operator/lb-name"
+
+	podSecurityLabelPrefix = "pod-security.kubernetes.io/"
+
+	labelValueLength = 63
+)
+
+// invalidLabelChars matches characters that are not permitted in a label value
+var invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// namespaceLabels returns the labels applied to the namespace of a loadbalancer. Operator
+// managed labels take precedence over the configured static labels.
+func (s *Server) namespaceLabels(name string, lb *loadBalancer) map[string]string {
+	labels := make(map[string]string, len(s.Namespace.Labels))
+
+	for k, v := range s.Namespace.Labels {
+		labels[k] = v
+	}
+
+	psa := s.Namespace.PodSecurity
+	for mode, level := range map[string]string{"enforce": psa.Enforce, "audit": psa.Audit, "warn": psa.Warn} {
+		if level == "" {
+			continue
+		}
+
+		labels[podSecurityLabelPrefix+mode] = level
+
+		if psa.Version != "" {
+			labels[podSecurityLabelPrefix+mode+"-version"] = psa.Version
+		}
+	}
+
+	labels[managedLabel] = "true"
+	labels[lbIDLabel] = name
+
+	if lb != nil && lb.lbData != nil {
+		setLabel(labels, ownerIDLabel, lb.lbData.Owner.ID)
+		setLabel(labels, locationIDLabel, lb.lbData.Location.ID)
+		setLabel(labels, lbNameLabel, lb.lbData.Name)
+	}
+
+	return labels
+}
+
+// namespaceAnnotations returns the annotations applied to the namespace of a loadbalancer
+func (s *Server) namespaceAnnotations(lb *loadBalancer) map[string]string {
+	annotations := make(map[string]string, len(s.Namespace.Annotations))
+
+	for k, v := range s.Namespace.Annotations {
+		annotations[k] = v
+	}
+
+	if lb != nil {
+		annotations[lbIDAnnotation] = lb.loadBalancerID.String()
+
+		if lb.names != nil {
+			annotations[namingSchemeAnnotation] = lb.names.scheme
+		}
+	}
+
+	return annotations
+}
+
+func setLabel(labels map[string]string, key, value string) {
+	if v := sanitizeLabelValue(value); v != "" {
+		labels[key] = v
+	}
+}
+
+// sanitizeLabelValue converts a value into a valid kubernetes label value by replacing
+// invalid characters, truncating to the label value limit and trimming characters that
+// may not start or end a label value
+func sanitizeLabelValue(value string) string {
+	v := invalidLabelChars.ReplaceAllString(value, "-")
+
+	if len(v) > labelValueLength {
+		v = v[:labelValueLength]
+	}
+
+	return strings.Trim(v, "._-")
+}
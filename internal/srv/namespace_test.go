@@ -0,0 +1,95 @@
+package srv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+)
+
+func (suite *srvTestSuite) TestNamespaceLabels() { //nolint:govet
+	id := gidx.MustNewID(LBPrefix)
+
+	lb := &loadBalancer{
+		loadBalancerID: id,
+		lbData: &lbapi.LoadBalancer{
+			ID:       id.String(),
+			Name:     "My Load Balancer!",
+			Owner:    lbapi.OwnerNode{ID: "tnntten-owner"},
+			Location: lbapi.LocationNode{ID: "lctnloc-testing"},
+		},
+	}
+
+	srv := Server{
+		Namespace: config.NamespaceConfig{
+			Labels: map[string]string{
+				"cost-center": "networking",
+				managedLabel:  "false",
+			},
+			PodSecurity: config.PodSecurityConfig{
+				Enforce: "baseline",
+				Warn:    "restricted",
+				Version: "latest",
+			},
+		},
+	}
+
+	labels := srv.namespaceLabels("lb-abc", lb)
+
+	assert.Equal(suite.T(), "networking", labels["cost-center"])
+	assert.Equal(suite.T(), "true", labels[managedLabel])
+	assert.Equal(suite.T(), "lb-abc", labels[lbIDLabel])
+	assert.Equal(suite.T(), "tnntten-owner", labels[ownerIDLabel])
+	assert.Equal(suite.T(), "lctnloc-testing", labels[locationIDLabel])
+	assert.Equal(suite.T(), "My-Load-Balancer", labels[lbNameLabel])
+	assert.Equal(suite.T(), "baseline", labels["pod-security.kubernetes.io/enforce"])
+	assert.Equal(suite.T(), "latest", labels["pod-security.kubernetes.io/enforce-version"])
+	assert.Equal(suite.T(), "restricted", labels["pod-security.kubernetes.io/warn"])
+	assert.NotContains(suite.T(), labels, "pod-security.kubernetes.io/audit")
+}
+
+func (suite *srvTestSuite) TestSanitizeLabelValue() { //nolint:govet
+	type testCase struct {
+		name   string
+		value  string
+		expect string
+	}
+
+	testCases := []testCase{
+		{
+			name:   "valid value",
+			value:  "my-lb.example_1",
+			expect: "my-lb.example_1",
+		},
+		{
+			name:   "invalid characters",
+			value:  "my lb/with:chars",
+			expect: "my-lb-with-chars",
+		},
+		{
+			name:   "leading and trailing separators",
+			value:  "--my-lb--",
+			expect: "my-lb",
+		},
+		{
+			name:   "too long",
+			value:  strings.Repeat("a", 70),
+			expect: strings.Repeat("a", labelValueLength),
+		},
+		{
+			name:   "empty",
+			value:  "!!!",
+			expect: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, sanitizeLabelValue(tc.value))
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package srv
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// lbNamePrefix is prepended to the hashed loadbalancer ID for namespace and release names
+	lbNamePrefix = "lb-"
+	// lbNameHashLength is the number of hex characters of the loadbalancer ID hash that are kept
+	lbNameHashLength = 20
+
+	managedLabel           = "com.infratographer.lb-operator/managed"
+	lbIDLabel              = "com.infratographer.lb-operator/lb-id"
+	lbIDAnnotation         = "com.infratographer.lb-operator/lb-id"
+	namingSchemeAnnotation = "com.infratographer.lb-operator/naming-scheme"
+
+	namingSchemeHash   = "hash"
+	namingSchemeLegacy = "legacy"
+)
+
+// lbNames holds the kubernetes namespace and helm release name used for a loadbalancer
+type lbNames struct {
+	namespace string
+	release   string
+	scheme    string
+}
+
+// hashLBName returns the namespace name for a loadbalancer. The name is a fixed length,
+// deterministic hash of the ID so that it always fits within kubernetes and helm limits.
+func hashLBName(name string) string {
+	sum := sha256.Sum256([]byte(name))
+
+	return lbNamePrefix + hex.EncodeToString(sum[:])[:lbNameHashLength]
+}
+
+// legacyHashLBName returns the namespace name used before names were hashed, which
+// hex encodes the full loadbalancer ID
+func legacyHashLBName(name string) string {
+	return hex.EncodeToString([]byte(name))
+}
+
+func newLBNames(id string) lbNames {
+	name := hashLBName(id)
+
+	return lbNames{namespace: name, release: name, scheme: namingSchemeHash}
+}
+
+func legacyLBNames(id string) lbNames {
+	hash := legacyHashLBName(id)
+
+	releaseName := fmt.Sprintf("lb-%s", hash)
+	if !checkNameLength(releaseName, helmReleaseLength) {
+		releaseName = releaseName[0:helmReleaseLength]
+	}
+
+	return lbNames{namespace: hash, release: releaseName, scheme: namingSchemeLegacy}
+}
+
+// lbNames resolves the namespace and release name for a loadbalancer. Namespaces that
+// were created with the legacy naming scheme are adopted so that existing releases
+// continue to be managed in place.
+func (s *Server) lbNames(ctx context.Context, lb *loadBalancer) (lbNames, error) {
+	if lb.names != nil {
+		return *lb.names, nil
+	}
+
+	id := lb.loadBalancerID.String()
+	names := newLBNames(id)
+
+	legacy := legacyLBNames(id)
+	if checkNameLength(legacy.namespace, kubeNSLength) {
+		adopt, err := s.hasLegacyNamespace(ctx, legacy.namespace)
+		if err != nil {
+			return lbNames{}, err
+		}
+
+		if adopt {
+			s.Logger.Debugw("adopting namespace created with legacy naming scheme", "namespace", legacy.namespace, "loadBalancer", id)
+			names = legacy
+		}
+	}
+
+	lb.names = &names
+
+	return names, nil
+}
+
+// hasLegacyNamespace reports whether a namespace managed by the operator exists with the legacy name
+func (s *Server) hasLegacyNamespace(ctx context.Context, name string) (bool, error) {
+	kc, err := kubernetes.NewForConfig(s.restConfig(ctx))
+	if err != nil {
+		s.Logger.Debugw("unable to authenticate against kubernetes cluster", "error", err)
+		return false, err
+	}
+
+	var found bool
+
+	err = s.withPhaseTimeout(ctx, phaseNamespace, func(ctx context.Context) error {
+		ns, err := kc.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+
+		switch {
+		case apierrors.IsNotFound(err):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		found = ns.Labels[managedLabel] == "true"
+
+		return nil
+	})
+
+	return found, err
+}
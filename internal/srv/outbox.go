@@ -0,0 +1,441 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+)
+
+const (
+	// DefaultOutboxInterval is how often pending status updates are checked for redelivery
+	DefaultOutboxInterval = 5 * time.Second
+	// DefaultOutboxMinBackoff is the delay before the first redelivery of a status update
+	DefaultOutboxMinBackoff = time.Second
+	// DefaultOutboxMaxBackoff is the longest delay between redeliveries of a status update
+	DefaultOutboxMaxBackoff = 5 * time.Minute
+	// DefaultOutboxBucket is the NATS KV bucket pending status updates are stored in
+	DefaultOutboxBucket = "load-balancer-operator-status-outbox"
+	// DefaultOutboxMaxPending is the number of status updates kept for a loadbalancer
+	DefaultOutboxMaxPending = 10
+
+	// outboxDropRejected is an update the metadata service will never accept
+	outboxDropRejected = "rejected"
+	// outboxDropCoalesced is an update replaced by newer updates of the loadbalancer
+	outboxDropCoalesced = "coalesced"
+)
+
+// outboxEntry is a status update that has not been delivered yet
+type outboxEntry struct {
+	Seq            uint64              `json:"seq"`
+	LoadBalancerID gidx.PrefixedID     `json:"loadBalancerID"`
+	Status         *LoadBalancerStatus `json:"status"`
+	Published      bool                `json:"published"`
+}
+
+// key is the KV key of the entry. Keys sort by sequence within a loadbalancer.
+func (e *outboxEntry) key() string {
+	return fmt.Sprintf("%s.%020d", e.LoadBalancerID, e.Seq)
+}
+
+type outboxRetry struct {
+	attempts int
+	next     time.Time
+}
+
+// outboxLock serializes delivery for a loadbalancer. It is removed from the outbox once
+// no delivery holds or waits for it.
+type outboxLock struct {
+	sync.Mutex
+	refs int
+}
+
+// StatusOutbox holds status updates until they have been published and written to the
+// metadata service. Updates are delivered in the order they were added for each
+// loadbalancer, a later update is never delivered before an earlier one, so an old state
+// cannot overwrite a newer one. When a NATS KV bucket is provided pending updates are
+// stored in the bucket and survive restarts. Once more than MaxPending updates are pending
+// for a loadbalancer the oldest ones waiting behind the update being delivered are dropped.
+type StatusOutbox struct {
+	Interval   time.Duration
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	MaxPending int
+
+	mu      sync.Mutex
+	kv      nats.KeyValue
+	seq     uint64
+	pending map[gidx.PrefixedID][]*outboxEntry
+	retries map[gidx.PrefixedID]outboxRetry
+	locks   map[gidx.PrefixedID]*outboxLock
+}
+
+// NewStatusOutbox returns an in-memory StatusOutbox
+func NewStatusOutbox() *StatusOutbox {
+	return &StatusOutbox{
+		Interval:   DefaultOutboxInterval,
+		MinBackoff: DefaultOutboxMinBackoff,
+		MaxBackoff: DefaultOutboxMaxBackoff,
+		MaxPending: DefaultOutboxMaxPending,
+		pending:    make(map[gidx.PrefixedID][]*outboxEntry),
+		retries:    make(map[gidx.PrefixedID]outboxRetry),
+		locks:      make(map[gidx.PrefixedID]*outboxLock),
+	}
+}
+
+// NewKVStatusOutbox returns a StatusOutbox backed by the provided NATS KV bucket. Updates
+// left in the bucket by a previous run are loaded and delivered first.
+func NewKVStatusOutbox(kv nats.KeyValue) (*StatusOutbox, error) {
+	o := NewStatusOutbox()
+	o.kv = kv
+
+	keys, err := kv.Keys()
+
+	switch {
+	case errors.Is(err, nats.ErrNoKeysFound):
+		return o, nil
+	case err != nil:
+		return nil, err
+	}
+
+	for _, key := range keys {
+		kve, err := kv.Get(key)
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		e := &outboxEntry{}
+		if err := json.Unmarshal(kve.Value(), e); err != nil {
+			return nil, fmt.Errorf("outbox entry %s: %w", key, err)
+		}
+
+		o.pending[e.LoadBalancerID] = append(o.pending[e.LoadBalancerID], e)
+
+		if e.Seq > o.seq {
+			o.seq = e.Seq
+		}
+	}
+
+	for _, entries := range o.pending {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	}
+
+	outboxPendingGauge.Set(float64(o.size()))
+
+	return o, nil
+}
+
+// NewOutboxKV creates or binds to the NATS KV bucket used to store pending status updates
+func NewOutboxKV(conn events.Connection, bucket string) (nats.KeyValue, error) {
+	return newKeyValue(conn, &nats.KeyValueConfig{
+		Bucket:      bucket,
+		Description: "load-balancer-operator pending status updates",
+	})
+}
+
+// add stores a status update behind any pending updates for the loadbalancer
+func (o *StatusOutbox) add(id gidx.PrefixedID, status *LoadBalancerStatus) (*outboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// sequences are time based so that they keep increasing across restarts
+	o.seq++
+	if now := uint64(time.Now().UnixNano()); now > o.seq {
+		o.seq = now
+	}
+
+	e := &outboxEntry{
+		Seq:            o.seq,
+		LoadBalancerID: id,
+		Status:         status,
+	}
+
+	if err := o.put(e); err != nil {
+		return nil, err
+	}
+
+	o.pending[id] = append(o.pending[id], e)
+	outboxPendingGauge.Inc()
+
+	o.coalesce(id)
+
+	return e, nil
+}
+
+// coalesce drops the oldest updates waiting behind the first pending update of a
+// loadbalancer once more than MaxPending are pending. The first update may be in the
+// middle of being delivered and the newest is the current state, both are always kept.
+// The caller must hold o.mu.
+func (o *StatusOutbox) coalesce(id gidx.PrefixedID) {
+	if o.MaxPending <= 0 {
+		return
+	}
+
+	entries := o.pending[id]
+
+	for len(entries) > max(o.MaxPending, 2) {
+		dropped := entries[1]
+		entries = append(entries[:1], entries[2:]...)
+
+		o.delete(dropped)
+		outboxPendingGauge.Dec()
+		statusUpdatesDroppedCounter.WithLabelValues(outboxDropCoalesced).Inc()
+	}
+
+	o.pending[id] = entries
+}
+
+// markPublished records that the event for an entry has been published so that it is not
+// published again when the metadata update is retried
+func (o *StatusOutbox) markPublished(e *outboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e.Published = true
+
+	return o.put(e)
+}
+
+// done removes a delivered entry
+func (o *StatusOutbox) done(e *outboxEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries := o.pending[e.LoadBalancerID]
+	for i, pending := range entries {
+		if pending == e {
+			entries = append(entries[:i], entries[i+1:]...)
+			outboxPendingGauge.Dec()
+
+			break
+		}
+	}
+
+	if len(entries) == 0 {
+		delete(o.pending, e.LoadBalancerID)
+		delete(o.retries, e.LoadBalancerID)
+	} else {
+		o.pending[e.LoadBalancerID] = entries
+	}
+
+	o.delete(e)
+}
+
+// failed delays the next delivery of the pending updates for a loadbalancer
+func (o *StatusOutbox) failed(id gidx.PrefixedID) time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	r := o.retries[id]
+	r.attempts++
+
+	delay := o.backoff(r.attempts)
+	r.next = time.Now().Add(delay)
+
+	o.retries[id] = r
+
+	return delay
+}
+
+// backoff doubles the delay for every failed attempt up to MaxBackoff
+func (o *StatusOutbox) backoff(attempts int) time.Duration {
+	delay := o.MinBackoff
+
+	for i := 1; i < attempts && delay < o.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if o.MaxBackoff > 0 && delay > o.MaxBackoff {
+		delay = o.MaxBackoff
+	}
+
+	return delay
+}
+
+// entries returns the pending updates for a loadbalancer in delivery order
+func (o *StatusOutbox) entries(id gidx.PrefixedID) []*outboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]*outboxEntry(nil), o.pending[id]...)
+}
+
+// due returns the loadbalancers with pending updates whose backoff has passed
+func (o *StatusOutbox) due(now time.Time) []gidx.PrefixedID {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	ids := make([]gidx.PrefixedID, 0, len(o.pending))
+
+	for id := range o.pending {
+		if o.backingOff(id, now) {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+// isDue reports whether the backoff of a loadbalancer has passed
+func (o *StatusOutbox) isDue(id gidx.PrefixedID, now time.Time) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return !o.backingOff(id, now)
+}
+
+// backingOff reports whether deliveries for a loadbalancer are delayed after a failure.
+// The caller must hold o.mu.
+func (o *StatusOutbox) backingOff(id gidx.PrefixedID, now time.Time) bool {
+	r, ok := o.retries[id]
+
+	return ok && now.Before(r.next)
+}
+
+// lock serializes delivery for a loadbalancer and returns the unlock function
+func (o *StatusOutbox) lock(id gidx.PrefixedID) func() {
+	o.mu.Lock()
+
+	l, ok := o.locks[id]
+	if !ok {
+		l = &outboxLock{}
+		o.locks[id] = l
+	}
+
+	l.refs++
+
+	o.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		if l.refs--; l.refs == 0 {
+			delete(o.locks, id)
+		}
+	}
+}
+
+func (o *StatusOutbox) size() int {
+	n := 0
+	for _, entries := range o.pending {
+		n += len(entries)
+	}
+
+	return n
+}
+
+// delete removes an entry from the KV bucket
+func (o *StatusOutbox) delete(e *outboxEntry) {
+	if o.kv != nil {
+		_ = o.kv.Delete(e.key())
+	}
+}
+
+func (o *StatusOutbox) put(e *outboxEntry) error {
+	if o.kv == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.kv.Put(e.key(), b)
+
+	return err
+}
+
+// flushOutbox attempts to deliver the pending status updates of a loadbalancer once in
+// order, stopping at the first update that cannot be delivered. Failed updates are retried
+// by runOutbox once the backoff of the loadbalancer has passed. Updates that fail with an
+// error that is not retryable are dropped so that they do not hold up later updates.
+func (s *Server) flushOutbox(ctx context.Context, id gidx.PrefixedID) error {
+	unlock := s.Outbox.lock(id)
+	defer unlock()
+
+	var rejected []error
+
+	for _, e := range s.Outbox.entries(id) {
+		err := s.withPhaseTimeout(ctx, phaseMetadata, func(ctx context.Context) error {
+			return s.deliverStatus(ctx, e)
+		})
+
+		switch {
+		case err == nil:
+			s.Outbox.done(e)
+		case !isRetryable(err) && ctx.Err() == nil:
+			statusUpdateFailuresCounter.WithLabelValues(string(e.Status.State)).Inc()
+			statusUpdatesDroppedCounter.WithLabelValues(outboxDropRejected).Inc()
+
+			s.Logger.Errorw("status update rejected, dropping it", "error", err, "loadBalancer", id.String(), "state", e.Status.State)
+			s.Outbox.done(e)
+
+			rejected = append(rejected, err)
+		default:
+			statusUpdateFailuresCounter.WithLabelValues(string(e.Status.State)).Inc()
+
+			delay := s.Outbox.failed(id)
+			s.Logger.Warnw("status update not delivered, retrying later", "error", err, "loadBalancer", id.String(), "state", e.Status.State, "retryIn", delay)
+
+			return errors.Join(append(rejected, err)...)
+		}
+	}
+
+	return errors.Join(rejected...)
+}
+
+// deliverStatus publishes the status event and writes the status to the metadata service
+func (s *Server) deliverStatus(ctx context.Context, e *outboxEntry) error {
+	if !e.Published {
+		if err := s.publishLoadBalancerMetadata(ctx, e.LoadBalancerID, e.Status); err != nil {
+			return err
+		}
+
+		if err := s.Outbox.markPublished(e); err != nil {
+			s.Logger.Debugw("unable to record published status update", "error", err, "loadBalancer", e.LoadBalancerID.String())
+		}
+	}
+
+	return s.metadataStatusUpdate(ctx, e.LoadBalancerID, e.Status)
+}
+
+// runOutbox redelivers pending status updates until ctx is canceled
+func (s *Server) runOutbox(ctx context.Context) {
+	interval := s.Outbox.Interval
+	if interval <= 0 {
+		interval = DefaultOutboxInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, id := range s.Outbox.due(time.Now()) {
+				_ = s.flushOutbox(ctx, id)
+			}
+		}
+	}
+}
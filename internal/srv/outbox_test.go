@@ -0,0 +1,194 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lbmeta "go.infratographer.com/load-balancer-api/pkg/metadata"
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+var errPublishUnavailable = errors.New("publish unavailable")
+
+// unreliablePublisher records published events and fails while down is set
+type unreliablePublisher struct {
+	publishedEvents
+
+	down     bool
+	attempts int
+
+	// rejected is a topic that is never published to
+	rejected string
+}
+
+func (p *unreliablePublisher) PublishEvent(ctx context.Context, topic string, msg events.EventMessage) (events.Message[events.EventMessage], error) {
+	p.attempts++
+
+	if p.down {
+		return nil, errPublishUnavailable
+	}
+
+	if topic == p.rejected {
+		return nil, apierrors.NewBadRequest("rejected")
+	}
+
+	return p.publishedEvents.PublishEvent(ctx, topic, msg)
+}
+
+func (suite *srvTestSuite) TestStatusOutboxKeepsOrder() { //nolint:govet
+	conn := &unreliablePublisher{down: true}
+	id := gidx.MustNewID(LBPrefix)
+
+	srv := Server{
+		Logger:           zap.NewNop().Sugar(),
+		EventsConnection: conn,
+		Outbox:           NewStatusOutbox(),
+	}
+
+	active := &LoadBalancerStatus{LoadBalancerStatus: lbmeta.LoadBalancerStatus{State: lbmeta.LoadBalancerStateActive}}
+	deleted := &LoadBalancerStatus{LoadBalancerStatus: lbmeta.LoadBalancerStatus{State: lbmeta.LoadBalancerStateDeleted}}
+
+	// undelivered updates are kept and the caller is told they were not delivered
+	assert.ErrorIs(suite.T(), srv.LoadBalancerStatusUpdate(context.TODO(), id, active), errPublishUnavailable)
+	assert.ErrorIs(suite.T(), srv.LoadBalancerStatusUpdate(context.TODO(), id, deleted), errStatusUpdateDelayed)
+
+	assert.Empty(suite.T(), conn.subjects)
+	assert.Len(suite.T(), srv.Outbox.entries(id), 2)
+
+	// the second update is queued without a delivery attempt while the first backs off
+	assert.Equal(suite.T(), 1, conn.attempts)
+
+	// the loadbalancer is backing off
+	assert.Empty(suite.T(), srv.Outbox.due(time.Now()))
+	assert.Equal(suite.T(), []gidx.PrefixedID{id}, srv.Outbox.due(time.Now().Add(time.Hour)))
+
+	conn.down = false
+
+	require.NoError(suite.T(), srv.flushOutbox(context.TODO(), id))
+
+	assert.Equal(suite.T(), []string{"load-balancer.active", "load-balancer.deleted"}, conn.subjects)
+	assert.Empty(suite.T(), srv.Outbox.entries(id))
+	assert.Empty(suite.T(), srv.Outbox.due(time.Now().Add(time.Hour)))
+	assert.Empty(suite.T(), srv.Outbox.locks, "delivery locks should be removed once unused")
+}
+
+func (suite *srvTestSuite) TestStatusOutboxDropsRejectedUpdates() { //nolint:govet
+	conn := &unreliablePublisher{down: true, rejected: "load-balancer.active"}
+	id := gidx.MustNewID(LBPrefix)
+
+	srv := Server{
+		Logger:           zap.NewNop().Sugar(),
+		EventsConnection: conn,
+		Outbox:           NewStatusOutbox(),
+	}
+
+	_, err := srv.Outbox.add(id, &LoadBalancerStatus{LoadBalancerStatus: lbmeta.LoadBalancerStatus{State: lbmeta.LoadBalancerStateActive}})
+	require.NoError(suite.T(), err)
+	_, err = srv.Outbox.add(id, &LoadBalancerStatus{LoadBalancerStatus: lbmeta.LoadBalancerStatus{State: lbmeta.LoadBalancerStateDeleted}})
+	require.NoError(suite.T(), err)
+
+	conn.down = false
+
+	// the rejected update is dropped and does not hold up the next one
+	err = srv.flushOutbox(context.TODO(), id)
+	assert.True(suite.T(), apierrors.IsBadRequest(err))
+
+	assert.Equal(suite.T(), []string{"load-balancer.deleted"}, conn.subjects)
+	assert.Empty(suite.T(), srv.Outbox.entries(id))
+	assert.Empty(suite.T(), srv.Outbox.retries)
+}
+
+func (suite *srvTestSuite) TestStatusOutboxCoalesce() { //nolint:govet
+	o := NewStatusOutbox()
+	o.MaxPending = 3
+
+	id := gidx.MustNewID(LBPrefix)
+
+	states := []lbmeta.LoadBalancerState{
+		lbmeta.LoadBalancerStateCreating,
+		lbmeta.LoadBalancerStateActive,
+		lbmeta.LoadBalancerStateUpdating,
+		lbmeta.LoadBalancerStateActive,
+		lbmeta.LoadBalancerStateTerminating,
+	}
+
+	for _, state := range states {
+		_, err := o.add(id, &LoadBalancerStatus{LoadBalancerStatus: lbmeta.LoadBalancerStatus{State: state}})
+		require.NoError(suite.T(), err)
+	}
+
+	// the first update and the newest ones are kept in order
+	entries := o.entries(id)
+	require.Len(suite.T(), entries, 3)
+	assert.Equal(suite.T(), lbmeta.LoadBalancerStateCreating, entries[0].Status.State)
+	assert.Equal(suite.T(), lbmeta.LoadBalancerStateActive, entries[1].Status.State)
+	assert.Equal(suite.T(), lbmeta.LoadBalancerStateTerminating, entries[2].Status.State)
+	assert.Less(suite.T(), entries[1].Seq, entries[2].Seq)
+}
+
+func (suite *srvTestSuite) TestStatusOutboxBackoff() { //nolint:govet
+	o := NewStatusOutbox()
+	o.MinBackoff = time.Second
+	o.MaxBackoff = 10 * time.Second
+
+	type testCase struct {
+		attempts int
+		expected time.Duration
+	}
+
+	testCases := []testCase{
+		{attempts: 1, expected: time.Second},
+		{attempts: 2, expected: 2 * time.Second},
+		{attempts: 4, expected: 8 * time.Second},
+		{attempts: 5, expected: 10 * time.Second},
+		{attempts: 50, expected: 10 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(suite.T(), tc.expected, o.backoff(tc.attempts), "attempts %d", tc.attempts)
+	}
+}
+
+func (suite *srvTestSuite) TestKVStatusOutbox() { //nolint:govet
+	kv, err := NewOutboxKV(suite.Connection, "status-outbox-test")
+	require.NoError(suite.T(), err)
+
+	first, second := gidx.MustNewID(LBPrefix), gidx.MustNewID(LBPrefix)
+
+	o, err := NewKVStatusOutbox(kv)
+	require.NoError(suite.T(), err)
+
+	_, err = o.add(first, &LoadBalancerStatus{LoadBalancerStatus: lbmeta.LoadBalancerStatus{State: lbmeta.LoadBalancerStateActive}})
+	require.NoError(suite.T(), err)
+
+	published, err := o.add(first, &LoadBalancerStatus{LoadBalancerStatus: lbmeta.LoadBalancerStatus{State: lbmeta.LoadBalancerStateDeleted}})
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), o.markPublished(published))
+
+	delivered, err := o.add(second, &LoadBalancerStatus{LoadBalancerStatus: lbmeta.LoadBalancerStatus{State: lbmeta.LoadBalancerStateActive}})
+	require.NoError(suite.T(), err)
+	o.done(delivered)
+
+	// pending updates are loaded in order after a restart
+	restarted, err := NewKVStatusOutbox(kv)
+	require.NoError(suite.T(), err)
+
+	entries := restarted.entries(first)
+	require.Len(suite.T(), entries, 2)
+	assert.Equal(suite.T(), lbmeta.LoadBalancerStateActive, entries[0].Status.State)
+	assert.False(suite.T(), entries[0].Published)
+	assert.Equal(suite.T(), lbmeta.LoadBalancerStateDeleted, entries[1].Status.State)
+	assert.True(suite.T(), entries[1].Published)
+	assert.Empty(suite.T(), restarted.entries(second))
+
+	// new updates are ordered after the loaded ones
+	next, err := restarted.add(first, &LoadBalancerStatus{})
+	require.NoError(suite.T(), err)
+	assert.Greater(suite.T(), next.Seq, entries[1].Seq)
+}
@@ -8,18 +8,188 @@ import (
 const subsystem = "load_balancer_operator"
 
 var (
-	numberLoadBalancersCreatedGauge = promauto.NewGauge(
-		prometheus.GaugeOpts{
+	loadBalancersCreatedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
 			Subsystem: subsystem,
-			Name:      "load_balancers_created",
+			Name:      "load_balancers_created_total",
 			Help:      "Total count of load balancers created",
 		},
 	)
-	numberLoadBalancersDeletedGauge = promauto.NewGauge(
-		prometheus.GaugeOpts{
+	loadBalancersDeletedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
 			Subsystem: subsystem,
-			Name:      "load_balancers_deleted",
+			Name:      "load_balancers_deleted_total",
 			Help:      "Total count of load balancers deleted",
 		},
 	)
+	messagesReceivedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "messages_received_total",
+			Help:      "Total count of messages received by topic and event type",
+		},
+		[]string{"topic", "event_type"},
+	)
+	messagesAckedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "messages_acked_total",
+			Help:      "Total count of messages acknowledged by topic and event type",
+		},
+		[]string{"topic", "event_type"},
+	)
+	messagesFailedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "messages_failed_total",
+			Help:      "Total count of messages that could not be prepared or acknowledged by topic and event type",
+		},
+		[]string{"topic", "event_type"},
+	)
+	taskDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "task_duration_seconds",
+			Help:      "Time taken to process a load balancer task by action and result",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12), //nolint:gomnd
+		},
+		[]string{"action", "result"},
+	)
+	helmDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "helm_operation_duration_seconds",
+			Help:      "Time taken by helm operations on load balancer releases by action and result",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12), //nolint:gomnd
+		},
+		[]string{"action", "result"},
+	)
+	runnerQueueDepthGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "runner_queue_depth",
+			Help:      "Number of tasks waiting in load balancer runner queues",
+		},
+	)
+	activeRunnersGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "active_runners",
+			Help:      "Number of running load balancer runners",
+		},
+	)
+	duplicateMessagesCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "duplicate_messages_skipped_total",
+			Help:      "Total count of redelivered or duplicate messages that were skipped",
+		},
+	)
+	unknownEventsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "unknown_events_total",
+			Help:      "Total count of events skipped because no handler is registered for them",
+		},
+		[]string{"event_type"},
+	)
+	supersededTasksCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "superseded_tasks_total",
+			Help:      "Total count of queued or in-flight tasks canceled by a delete for the same load balancer",
+		},
+	)
+	phaseTimeoutsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "phase_timeouts_total",
+			Help:      "Total count of load balancer processing phases that exceeded their timeout",
+		},
+		[]string{"phase"},
+	)
+	operationRetriesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "operation_retries_total",
+			Help:      "Total count of operations retried after a retryable failure",
+		},
+		[]string{"operation"},
+	)
+	stuckNamespacesGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "namespaces_stuck_terminating",
+			Help:      "Number of deleted load balancer namespaces that did not terminate within the timeout",
+		},
+	)
+	namespaceTerminationDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "namespace_termination_seconds",
+			Help:      "Time taken for a deleted load balancer namespace to terminate",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10), //nolint:gomnd
+		},
+	)
+	clusterUpGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "cluster_up",
+			Help:      "Whether the API server of a deployment cluster was reachable at the last readiness check",
+		},
+		[]string{"cluster"},
+	)
+	clusterOperationsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "cluster_operations_total",
+			Help:      "Total count of operations performed against each deployment cluster by result",
+		},
+		[]string{"cluster", "operation", "result"},
+	)
+	outboxPendingGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "status_outbox_pending",
+			Help:      "Number of load balancer status updates waiting to be delivered",
+		},
+	)
+	statusUpdateFailuresCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "status_update_failures_total",
+			Help:      "Total count of failed attempts to deliver a load balancer status update by state",
+		},
+		[]string{"state"},
+	)
+	statusUpdatesDroppedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "status_updates_dropped_total",
+			Help:      "Total count of load balancer status updates dropped from the outbox without being delivered by reason",
+		},
+		[]string{"reason"},
+	)
+	pausedLoadBalancersGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "load_balancer_paused",
+			Help:      "Set for each load balancer whose processing has been paused through the admin API",
+		},
+		[]string{"load_balancer", "mode"},
+	)
+	pausedTasksSkippedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "paused_tasks_skipped_total",
+			Help:      "Total count of tasks dropped because their load balancer was paused in skip mode",
+		},
+	)
+	auditWriteFailuresCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "audit_write_failures_total",
+			Help:      "Total count of audit records that could not be written",
+		},
+	)
 )
@@ -0,0 +1,211 @@
+package srv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+)
+
+const (
+	// readinessCheckTimeout bounds each readiness check
+	readinessCheckTimeout = 5 * time.Second
+	// readinessPollInterval is how often checks are repeated while waiting to start
+	readinessPollInterval = 5 * time.Second
+)
+
+// requiredPermissions are the cluster wide permissions needed to deploy loadbalancers. They
+// are granted by the ClusterRole of the chart; permissions within a loadbalancer namespace,
+// such as managing the helm release secrets, come from the role binding the operator creates
+// in it and cannot be checked before the namespace exists.
+var requiredPermissions = []authorizationv1.ResourceAttributes{
+	{Verb: "create", Resource: "namespaces"},
+	{Verb: "delete", Resource: "namespaces"},
+	{Verb: "patch", Resource: "namespaces"},
+	{Verb: "patch", Group: "rbac.authorization.k8s.io", Resource: "rolebindings"},
+	{Verb: "create", Resource: "events"},
+}
+
+// readinessChecks returns the checks that must pass before the operator processes messages
+func (s *Server) readinessChecks() map[string]func(context.Context) error {
+	checks := map[string]func(context.Context) error{
+		"events":     s.eventsCheck,
+		"kubernetes": kubernetesCheck(defaultClusterName, s.KubeClient),
+		"chart":      s.chartCheck,
+	}
+
+	if s.SupergraphEndpoint != "" {
+		checks["load-balancer-api"] = endpointCheck(s.SupergraphEndpoint)
+	}
+
+	if config.AppConfig.Metadata.Endpoint != "" {
+		checks["metadata-api"] = endpointCheck(config.AppConfig.Metadata.Endpoint)
+	}
+
+	for _, c := range s.Clusters {
+		checks["cluster-"+c.Name] = clusterCheck(c)
+	}
+
+	return checks
+}
+
+// waitUntilReady blocks until every readiness check passes so that no work is taken
+// on before the operator is able to complete it
+func (s *Server) waitUntilReady(ctx context.Context, checks map[string]func(context.Context) error) error {
+	ticker := time.NewTicker(readinessPollInterval)
+	defer ticker.Stop()
+
+	for {
+		failed := runChecks(ctx, checks)
+		if len(failed) == 0 {
+			return nil
+		}
+
+		s.Logger.Warnw("waiting for readiness checks to pass", "failed", failed)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// runChecks returns the failure of each check that did not pass, ordered by check name
+func runChecks(ctx context.Context, checks map[string]func(context.Context) error) []string {
+	failed := []string{}
+
+	for name, check := range checks {
+		if err := check(ctx); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+
+	sort.Strings(failed)
+
+	return failed
+}
+
+// eventsCheck reports whether the events connection is connected
+func (s *Server) eventsCheck(_ context.Context) error {
+	if s.EventsConnection == nil {
+		return errEventsNotConnected
+	}
+
+	// only nats connections report their state
+	nc, ok := s.EventsConnection.Source().(*nats.Conn)
+	if !ok {
+		return nil
+	}
+
+	if status := nc.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("%w: %s", errEventsNotConnected, status)
+	}
+
+	return nil
+}
+
+// chartCheck reports whether a valid loadbalancer chart is loaded
+func (s *Server) chartCheck(_ context.Context) error {
+	if s.Chart == nil {
+		return errChartNotLoaded
+	}
+
+	if err := s.Chart.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", errChartNotLoaded, err)
+	}
+
+	return nil
+}
+
+// kubernetesCheck reports whether the API server of a cluster can be reached and the
+// operator has the permissions it needs
+func kubernetesCheck(name string, cfg *rest.Config) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+		defer cancel()
+
+		if cfg == nil {
+			return fmt.Errorf("%w: %s", errInvalidClusterConfig, name)
+		}
+
+		kc, err := kubernetes.NewForConfig(cfg)
+		if err != nil {
+			return err
+		}
+
+		if _, err := kc.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx); err != nil {
+			return err
+		}
+
+		return checkPermissions(ctx, kc)
+	}
+}
+
+// checkPermissions reports the required permissions the operator does not have
+func checkPermissions(ctx context.Context, client kubernetes.Interface) error {
+	denied := []string{}
+
+	for _, attrs := range requiredPermissions {
+		attrs := attrs
+
+		review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attrs},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+
+		if !review.Status.Allowed {
+			resource := attrs.Resource
+			if attrs.Group != "" {
+				resource += "." + attrs.Group
+			}
+
+			denied = append(denied, attrs.Verb+" "+resource)
+		}
+	}
+
+	if len(denied) > 0 {
+		return fmt.Errorf("%w: %s", errMissingPermissions, strings.Join(denied, ", "))
+	}
+
+	return nil
+}
+
+// endpointCheck reports whether an HTTP endpoint answers. Any response other than a
+// server error means the service is up, requests are not authenticated.
+func endpointCheck(endpoint string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%w: %s returned %s", errEndpointUnavailable, endpoint, resp.Status)
+		}
+
+		return nil
+	}
+}
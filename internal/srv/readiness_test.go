@@ -0,0 +1,149 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slices"
+	"helm.sh/helm/v3/pkg/chart"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+var errCheckFailed = errors.New("check failed")
+
+func (suite *srvTestSuite) TestEventsCheck() { //nolint:govet
+	assert.NoError(suite.T(), (&Server{EventsConnection: suite.Connection}).eventsCheck(context.TODO()))
+	assert.ErrorIs(suite.T(), (&Server{}).eventsCheck(context.TODO()), errEventsNotConnected)
+}
+
+func (suite *srvTestSuite) TestChartCheck() { //nolint:govet
+	type testCase struct {
+		name        string
+		chart       *chart.Chart
+		expectError bool
+	}
+
+	testCases := []testCase{
+		{name: "no chart", expectError: true},
+		{name: "invalid chart", chart: &chart.Chart{Metadata: &chart.Metadata{}}, expectError: true},
+		{name: "valid chart", chart: &chart.Chart{Metadata: &chart.Metadata{Name: "lb", Version: "1.0.0", APIVersion: chart.APIVersionV2}}},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			err := (&Server{Chart: tcase.chart}).chartCheck(context.TODO())
+
+			if tcase.expectError {
+				assert.ErrorIs(t, err, errChartNotLoaded)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestEndpointCheck() { //nolint:govet
+	type testCase struct {
+		name        string
+		status      int
+		expectError bool
+	}
+
+	testCases := []testCase{
+		{name: "ok", status: http.StatusOK},
+		{name: "unauthenticated request", status: http.StatusUnauthorized},
+		{name: "server error", status: http.StatusBadGateway, expectError: true},
+	}
+
+	for _, tcase := range testCases {
+		suite.T().Run(tcase.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tcase.status)
+			}))
+			defer ts.Close()
+
+			err := endpointCheck(ts.URL)(context.TODO())
+
+			if tcase.expectError {
+				assert.ErrorIs(t, err, errEndpointUnavailable)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestCheckPermissions() { //nolint:govet
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Resource != "rolebindings"
+
+		return true, review, nil
+	})
+
+	err := checkPermissions(context.TODO(), client)
+	assert.ErrorIs(suite.T(), err, errMissingPermissions)
+	assert.ErrorContains(suite.T(), err, "patch rolebindings.rbac.authorization.k8s.io")
+}
+
+func (suite *srvTestSuite) TestRunChecks() { //nolint:govet
+	failed := runChecks(context.TODO(), map[string]func(context.Context) error{
+		"ok":     func(context.Context) error { return nil },
+		"zeta":   func(context.Context) error { return errCheckFailed },
+		"alpha":  func(context.Context) error { return errCheckFailed },
+		"passes": func(context.Context) error { return nil },
+	})
+
+	assert.Equal(suite.T(), []string{"alpha: check failed", "zeta: check failed"}, failed)
+}
+
+func (suite *srvTestSuite) TestRequiredPermissionsGrantedByChart() { //nolint:govet
+	manifest, err := os.ReadFile("../../chart/load-balancer-operator/templates/rbac.yaml")
+	require.NoError(suite.T(), err)
+
+	var role rbacv1.ClusterRole
+
+	for _, doc := range strings.Split(string(manifest), "\n---\n") {
+		// template directives are only used for names and labels
+		lines := []string{}
+
+		for _, line := range strings.Split(doc, "\n") {
+			if !strings.Contains(line, "{{") {
+				lines = append(lines, line)
+			}
+		}
+
+		require.NoError(suite.T(), yaml.Unmarshal([]byte(strings.Join(lines, "\n")), &role))
+
+		if role.Kind == "ClusterRole" {
+			break
+		}
+	}
+
+	require.Equal(suite.T(), "ClusterRole", role.Kind)
+
+	for _, attrs := range requiredPermissions {
+		granted := slices.ContainsFunc(role.Rules, func(rule rbacv1.PolicyRule) bool {
+			return slices.Contains(rule.APIGroups, attrs.Group) &&
+				slices.Contains(rule.Resources, attrs.Resource) &&
+				slices.Contains(rule.Verbs, attrs.Verb)
+		})
+
+		assert.True(suite.T(), granted, "%s %s is not granted by the chart", attrs.Verb, attrs.Resource)
+	}
+}
@@ -0,0 +1,93 @@
+package srv
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// eventComponent is the source component of the kubernetes events recorded by the operator
+	eventComponent = "load-balancer-operator"
+
+	messageIDAnnotation = "com.infratographer.lb-operator/message-id"
+
+	eventReasonInstalled       = "Installed"
+	eventReasonInstallFailed   = "InstallFailed"
+	eventReasonUpgraded        = "Upgraded"
+	eventReasonUpgradeFailed   = "UpgradeFailed"
+	eventReasonRolledBack      = "RolledBack"
+	eventReasonRollbackFailed  = "RollbackFailed"
+	eventReasonUninstalled     = "Uninstalled"
+	eventReasonUninstallFailed = "UninstallFailed"
+	eventReasonFailed          = "Failed"
+	eventReasonSkipped         = "Skipped"
+)
+
+type messageIDKey struct{}
+
+// withMessageID returns a context carrying the ID of the message that triggered the work
+func withMessageID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, messageIDKey{}, id)
+}
+
+// messageIDFromContext returns the ID of the message that triggered the work, if any
+func messageIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(messageIDKey{}).(string)
+
+	return id
+}
+
+// NewEventRecorder returns a recorder that writes kubernetes events to the cluster. The
+// broadcaster should be shut down when the operator exits.
+func NewEventRecorder(cfg *rest.Config) (record.EventBroadcaster, record.EventRecorder, error) {
+	kc, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kc.CoreV1().Events("")})
+
+	return broadcaster, broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventComponent}), nil
+}
+
+// recordEvent records a kubernetes event on the namespace of the loadbalancer. Events are
+// annotated with the loadbalancer ID and the ID of the message that triggered them.
+func (s *Server) recordEvent(ctx context.Context, lb *loadBalancer, eventType, reason, messageFmt string, args ...interface{}) {
+	recorder := s.eventRecorder(ctx)
+	if recorder == nil || lb == nil {
+		return
+	}
+
+	names, err := s.lbNames(ctx, lb)
+	if err != nil {
+		s.logger(ctx).Debugw("unable to resolve namespace for event", "error", err, "loadBalancer", lb.loadBalancerID.String(), "reason", reason)
+		return
+	}
+
+	annotations := map[string]string{
+		lbIDAnnotation: lb.loadBalancerID.String(),
+	}
+
+	if id := messageIDFromContext(ctx); id != "" {
+		annotations[messageIDAnnotation] = id
+	}
+
+	recorder.AnnotatedEventf(namespaceRef(names.namespace), annotations, eventType, reason, messageFmt, args...)
+}
+
+// namespaceRef references a loadbalancer namespace so that its events are stored within it
+func namespaceRef(name string) *v1.ObjectReference {
+	return &v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Namespace",
+		Name:       name,
+		Namespace:  name,
+	}
+}
@@ -0,0 +1,162 @@
+package srv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func (suite *srvTestSuite) TestRecordEvent() { //nolint:govet
+	type testCase struct {
+		name      string
+		messageID string
+		expect    []string
+	}
+
+	id := gidx.MustNewID(LBPrefix)
+	names := newLBNames(id.String())
+
+	testCases := []testCase{
+		{
+			name:      "with message id",
+			messageID: "stream/42",
+			expect: []string{
+				"Normal Installed installed release " + names.release,
+				"involvedObject{kind=Namespace,apiVersion=v1}",
+				id.String(),
+				"stream/42",
+			},
+		},
+		{
+			name: "without message id",
+			expect: []string{
+				"Normal Installed installed release " + names.release,
+				id.String(),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			recorder.IncludeObject = true
+
+			srv := Server{
+				Logger:        zap.NewNop().Sugar(),
+				EventRecorder: recorder,
+			}
+
+			lb := &loadBalancer{loadBalancerID: id, lbType: typeLB, names: &names}
+
+			ctx := context.TODO()
+			if tc.messageID != "" {
+				ctx = withMessageID(ctx, tc.messageID)
+			}
+
+			srv.recordEvent(ctx, lb, v1.EventTypeNormal, eventReasonInstalled, "installed release %s", names.release)
+
+			evt := <-recorder.Events
+			for _, e := range tc.expect {
+				assert.Contains(t, evt, e)
+			}
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestRollbackRelease() { //nolint:govet
+	type testCase struct {
+		name           string
+		history        []release.Status
+		expectRevision int
+		expectEvent    string
+	}
+
+	testCases := []testCase{
+		{
+			name:           "failed upgrade is rolled back",
+			history:        []release.Status{release.StatusSuperseded, release.StatusFailed},
+			expectRevision: 3,
+			expectEvent:    "Normal RolledBack rolled back release %s to revision 1",
+		},
+		{
+			name:           "repeated failed upgrades are rolled back to the last deployed revision",
+			history:        []release.Status{release.StatusSuperseded, release.StatusFailed, release.StatusFailed},
+			expectRevision: 4,
+			expectEvent:    "Normal RolledBack rolled back release %s to revision 1",
+		},
+		{
+			name:           "deployed release is kept",
+			history:        []release.Status{release.StatusSuperseded, release.StatusDeployed},
+			expectRevision: 2,
+		},
+		{
+			name:           "release that was never deployed is kept",
+			history:        []release.Status{release.StatusFailed, release.StatusFailed},
+			expectRevision: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			id := gidx.MustNewID(LBPrefix)
+			names := newLBNames(id.String())
+			recorder := record.NewFakeRecorder(1)
+
+			srv := Server{
+				Logger:        zap.NewNop().Sugar(),
+				EventRecorder: recorder,
+			}
+
+			client := &action.Configuration{
+				Releases:     storage.Init(driver.NewMemory()),
+				KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+				Capabilities: chartutil.DefaultCapabilities,
+				Log:          func(string, ...interface{}) {},
+			}
+
+			lbChart := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "lb", Version: "0.1.0"}}
+
+			for i, status := range tc.history {
+				require.NoError(t, client.Releases.Create(&release.Release{
+					Name:      names.release,
+					Namespace: names.namespace,
+					Version:   i + 1,
+					Chart:     lbChart,
+					Info:      &release.Info{Status: status},
+				}))
+			}
+
+			lb := &loadBalancer{loadBalancerID: id, lbType: typeLB, names: &names}
+
+			require.NoError(t, srv.rollbackRelease(context.TODO(), client, lb, names.namespace, names.release))
+
+			latest, err := client.Releases.Last(names.release)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectRevision, latest.Version)
+
+			if tc.expectEvent == "" {
+				assert.Empty(t, recorder.Events)
+				return
+			}
+
+			assert.Equal(t, tc.expectRevision, lb.revision)
+			require.Len(t, recorder.Events, 1)
+			assert.Contains(t, <-recorder.Events, fmt.Sprintf(tc.expectEvent, names.release))
+		})
+	}
+}
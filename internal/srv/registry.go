@@ -0,0 +1,67 @@
+package srv
+
+// lockRegistry locks LoadBalancers for writing and returns the unlock function
+func (s *Server) lockRegistry() func() {
+	s.registryMu.Lock()
+
+	return s.registryMu.Unlock
+}
+
+// removeRunner removes the runner of a loadbalancer from LoadBalancers and returns it
+func (s *Server) removeRunner(id string) *runner {
+	unlock := s.lockRegistry()
+	defer unlock()
+
+	r, ok := s.LoadBalancers[id]
+	if !ok {
+		return nil
+	}
+
+	delete(s.LoadBalancers, id)
+
+	return r
+}
+
+// removeIdleRunner removes r from LoadBalancers when it is still the runner of the
+// loadbalancer and has not been given new work since generation. False is returned,
+// and the runner left in place, otherwise.
+func (s *Server) removeIdleRunner(id string, r *runner, generation uint64) bool {
+	unlock := s.lockRegistry()
+	defer unlock()
+
+	if cur, ok := s.LoadBalancers[id]; !ok || cur != r || r.currentGeneration() != generation {
+		return false
+	}
+
+	delete(s.LoadBalancers, id)
+
+	return true
+}
+
+// runners returns a copy of LoadBalancers
+func (s *Server) runners() map[string]*runner {
+	s.registryMu.RLock()
+	defer s.registryMu.RUnlock()
+
+	runners := make(map[string]*runner, len(s.LoadBalancers))
+
+	for id, r := range s.LoadBalancers {
+		runners[id] = r
+	}
+
+	return runners
+}
+
+// trackedLoadBalancers returns the loadbalancers that have a runner, keyed by ID
+func (s *Server) trackedLoadBalancers() map[string]*loadBalancer {
+	runners := s.runners()
+	lbs := make(map[string]*loadBalancer, len(runners))
+
+	for id, r := range runners {
+		if lb := r.loadBalancer(); lb != nil {
+			lbs[id] = lb
+		}
+	}
+
+	return lbs
+}
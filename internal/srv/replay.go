@@ -0,0 +1,219 @@
+package srv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/events"
+)
+
+const (
+	replayOutcomeProcessed = "processed"
+	replayOutcomeFailed    = "failed"
+	replayOutcomeSkipped   = "skipped"
+	replayOutcomeIgnored   = "ignored"
+	replayOutcomeDryRun    = "dry-run"
+
+	// replayMaxLineSize is the longest message a replay file can hold
+	replayMaxLineSize = 1024 * 1024
+)
+
+// replayRecord is a line of a replay file. Exactly one of the messages is set.
+type replayRecord struct {
+	Change *events.ChangeMessage `json:"change,omitempty"`
+	Event  *events.EventMessage  `json:"event,omitempty"`
+}
+
+// ReplayResult describes what happened to a replayed message
+type ReplayResult struct {
+	Line           int    `json:"line"`
+	EventType      string `json:"eventType"`
+	SubjectID      string `json:"subjectID"`
+	LoadBalancerID string `json:"loadBalancerID,omitempty"`
+	Handler        string `json:"handler,omitempty"`
+	Outcome        string `json:"outcome"`
+	Error          string `json:"error,omitempty"`
+}
+
+// Replay processes the messages of a JSON lines file in order, as if they were received
+// from the events connection. Each line holds a change or an event message:
+//
+//	{"change": {"subjectID": "loadbal-...", "eventType": "create", ...}}
+//	{"event": {"subjectID": "ipamipa-...", "eventType": "ip-address.assigned", ...}}
+//
+// Tasks run one at a time rather than on the runner of their loadbalancer. With dryRun the
+// handler that would run each task is reported and nothing is changed.
+func (s *Server) Replay(ctx context.Context, r io.Reader, dryRun bool) ([]ReplayResult, error) {
+	results := []ReplayResult{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), replayMaxLineSize)
+
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		rec := replayRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return results, fmt.Errorf("line %d: %w", line, errors.Join(err, errInvalidReplayRecord))
+		}
+
+		var result ReplayResult
+
+		switch {
+		case rec.Change != nil && rec.Event == nil:
+			result = replayMessage(ctx, s, *rec.Change, line, dryRun)
+		case rec.Event != nil && rec.Change == nil:
+			result = replayMessage(ctx, s, *rec.Event, line, dryRun)
+		default:
+			return results, fmt.Errorf("line %d: %w", line, errInvalidReplayRecord)
+		}
+
+		s.Logger.Infow("replayed message", "line", result.Line, "event", result.EventType, "subjectID", result.SubjectID,
+			"loadBalancer", result.LoadBalancerID, "handler", result.Handler, "outcome", result.Outcome, "error", result.Error)
+
+		results = append(results, result)
+	}
+
+	return results, scanner.Err()
+}
+
+// Failed reports whether the message could not be prepared or its task failed
+func (r ReplayResult) Failed() bool {
+	return r.Outcome == replayOutcomeFailed
+}
+
+// replayMessage prepares and processes a single replayed message
+func replayMessage[M Message](ctx context.Context, s *Server, msg M, line int, dryRun bool) ReplayResult {
+	result := ReplayResult{
+		Line:      line,
+		EventType: msg.GetEventType(),
+		SubjectID: msg.GetSubject().String(),
+	}
+
+	ctx = msg.GetTraceContext(ctx)
+
+	lb, err := prepareLoadBalancer(ctx, msg, s)
+
+	switch {
+	case errors.Is(err, errNotMyMessage):
+		result.Outcome = replayOutcomeIgnored
+		return result
+	case err != nil:
+		result.Outcome = replayOutcomeFailed
+		result.Error = err.Error()
+
+		return result
+	case lb.lbType == typeNoLB:
+		result.Outcome = replayOutcomeIgnored
+		return result
+	}
+
+	result.LoadBalancerID = lb.loadBalancerID.String()
+
+	ctx = withMessageID(ctx, fmt.Sprintf("replay/%d", line))
+	ctx = withCluster(ctx, s.clusterFor(lb, msg.GetAddSubjects()))
+
+	t := newTask(ctx, s, lb, msg.GetEventType(), msg.GetSubject())
+	defer t.cancelTask()
+
+	h, err := taskHandlers.plan(t)
+	if h != nil {
+		result.Handler = h.name
+	}
+
+	switch {
+	case err != nil:
+		result.Outcome = replayOutcomeSkipped
+		result.Error = err.Error()
+	case dryRun:
+		result.Outcome = replayOutcomeDryRun
+	default:
+		process(t)
+
+		result.Outcome = replayOutcomeProcessed
+
+		if t.err != nil {
+			result.Outcome = replayOutcomeFailed
+			result.Error = t.err.Error()
+		}
+	}
+
+	return result
+}
+
+// LoadFixtures reads loadbalancer fixtures from a JSON array of the loadBalancer objects
+// returned by the load-balancer-api, keyed by their ID
+func LoadFixtures(r io.Reader) (map[string]json.RawMessage, error) {
+	raw := []json.RawMessage{}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, errors.Join(err, errInvalidFixture)
+	}
+
+	fixtures := make(map[string]json.RawMessage, len(raw))
+
+	for _, f := range raw {
+		lb := struct {
+			ID string `json:"id"`
+		}{}
+
+		if err := json.Unmarshal(f, &lb); err != nil || lb.ID == "" {
+			return nil, errors.Join(err, errInvalidFixture)
+		}
+
+		fixtures[lb.ID] = f
+	}
+
+	return fixtures, nil
+}
+
+// NewFixtureClient returns a load-balancer-api client that answers loadbalancer lookups
+// from fixtures instead of the API. Loadbalancers without a fixture are not found.
+func NewFixtureClient(fixtures map[string]json.RawMessage) *lbapi.Client {
+	return lbapi.NewClient("http://fixtures.invalid/query", lbapi.WithHTTPClient(&http.Client{
+		Transport: fixtureTransport(fixtures),
+	}))
+}
+
+// fixtureTransport answers graphql loadbalancer queries from fixtures
+type fixtureTransport map[string]json.RawMessage
+
+func (f fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	defer req.Body.Close()
+
+	query := struct {
+		Variables struct {
+			ID string `json:"id"`
+		} `json:"variables"`
+	}{}
+
+	if err := json.NewDecoder(req.Body).Decode(&query); err != nil {
+		return nil, err
+	}
+
+	body := []byte(`{"data":null,"errors":[{"message":"load_balancer not found","path":["loadBalancer"]}]}`)
+
+	if lb, ok := f[query.Variables.ID]; ok {
+		body = []byte(`{"data":{"loadBalancer":` + string(lb) + `}}`)
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     http.StatusText(http.StatusOK),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package srv
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+func (suite *srvTestSuite) TestReplay() { //nolint:govet
+	id := gidx.MustNewID(LBPrefix)
+	missing := gidx.MustNewID(LBPrefix)
+	location := gidx.MustNewID("lctnloc")
+
+	fixtures, err := LoadFixtures(strings.NewReader(fmt.Sprintf(`[{"id": %q, "name": "test", "location": {"id": %q}}]`, id, location)))
+	require.NoError(suite.T(), err)
+
+	srv := &Server{
+		APIClient: NewFixtureClient(fixtures),
+		Context:   context.TODO(),
+		Logger:    zap.NewNop().Sugar(),
+	}
+
+	type testCase struct {
+		name            string
+		input           string
+		expectedResults []ReplayResult
+		expectedError   error
+	}
+
+	testCases := []testCase{
+		{
+			name:  "create from fixture",
+			input: fmt.Sprintf(`{"change": {"subjectID": %q, "eventType": "create"}}`, id),
+			expectedResults: []ReplayResult{
+				{Line: 1, EventType: "create", SubjectID: id.String(), LoadBalancerID: id.String(), Handler: "create", Outcome: replayOutcomeDryRun},
+			},
+		},
+		{
+			name:  "unknown event type",
+			input: fmt.Sprintf(`{"change": {"subjectID": %q, "eventType": "unknown"}}`, id),
+			expectedResults: []ReplayResult{
+				{Line: 1, EventType: "unknown", SubjectID: id.String(), LoadBalancerID: id.String(), Outcome: replayOutcomeSkipped, Error: errNoHandler.Error()},
+			},
+		},
+		{
+			name:  "loadbalancer without fixture",
+			input: fmt.Sprintf("\n"+`{"change": {"subjectID": %q, "eventType": "update"}}`, missing),
+			expectedResults: []ReplayResult{
+				{Line: 2, EventType: "update", SubjectID: missing.String(), Outcome: replayOutcomeFailed, Error: fmt.Errorf("%w: %w", errLoadBalancerInit, lbapi.ErrLBNotfound).Error()},
+			},
+		},
+		{
+			name:            "both messages",
+			input:           fmt.Sprintf(`{"change": {"subjectID": %q}, "event": {"subjectID": %q}}`, id, id),
+			expectedResults: []ReplayResult{},
+			expectedError:   errInvalidReplayRecord,
+		},
+		{
+			name:            "invalid json",
+			input:           `{"change":`,
+			expectedResults: []ReplayResult{},
+			expectedError:   errInvalidReplayRecord,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			results, err := srv.Replay(context.TODO(), strings.NewReader(tc.input), true)
+
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedResults, results)
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestLoadFixtures() { //nolint:govet
+	_, err := LoadFixtures(strings.NewReader(`[{"name": "no id"}]`))
+	assert.ErrorIs(suite.T(), err, errInvalidFixture)
+
+	_, err = LoadFixtures(strings.NewReader(`{"id": "not an array"}`))
+	assert.ErrorIs(suite.T(), err, errInvalidFixture)
+}
@@ -0,0 +1,185 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"golang.org/x/exp/slices"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+)
+
+// Operation identifies an action that is retried according to its own policy
+type Operation string
+
+const (
+	// OpInstall is a helm install of a loadbalancer release
+	OpInstall Operation = "install"
+	// OpUpgrade is a helm upgrade of a loadbalancer release
+	OpUpgrade Operation = "upgrade"
+	// OpRollback is a helm rollback of a loadbalancer release after a failed upgrade
+	OpRollback Operation = "rollback"
+	// OpUninstall is a helm uninstall of a loadbalancer release
+	OpUninstall Operation = "uninstall"
+	// OpNamespaceDelete is the removal of a loadbalancer namespace
+	OpNamespaceDelete Operation = "namespace-delete"
+	// OpMetadataUpdate is a loadbalancer status update in the metadata service
+	OpMetadataUpdate Operation = "metadata-update"
+)
+
+// terminalErrors will not succeed no matter how many times the operation is repeated
+var terminalErrors = []error{
+	errInvalidObjectNameLength,
+	errInvalidHelmValues,
+	errInvalidQuantity,
+	errLoadBalancerTerminating,
+	errNotMyMessage,
+	driver.ErrReleaseExists,
+	driver.ErrReleaseNotFound,
+	lbapi.ErrLBNotfound,
+	lbapi.ErrUnauthorized,
+	lbapi.ErrPermissionDenied,
+	context.Canceled,
+}
+
+// isRetryable reports whether an operation that failed with err may succeed if
+// it is attempted again. Timeouts and unclassified errors are retried.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, terminal := range terminalErrors {
+		if errors.Is(err, terminal) {
+			return false
+		}
+	}
+
+	switch {
+	case apierrors.IsNotFound(err),
+		apierrors.IsInvalid(err),
+		apierrors.IsBadRequest(err),
+		apierrors.IsForbidden(err),
+		apierrors.IsUnauthorized(err):
+		return false
+	default:
+		return true
+	}
+}
+
+// NewRetryPolicy returns an exponential backoff policy from the provided config.
+// A policy without retries runs the operation once.
+func NewRetryPolicy(cfg config.RetryPolicyConfig) backoff.Policy {
+	if cfg.MaxRetries <= 0 {
+		return backoff.Null()
+	}
+
+	return backoff.Exponential(
+		backoff.WithMinInterval(cfg.MinInterval),
+		backoff.WithMaxInterval(cfg.MaxInterval),
+		backoff.WithJitterFactor(cfg.Jitter),
+		backoff.WithMaxRetries(cfg.MaxRetries),
+	)
+}
+
+// NewRetryPolicies returns the retry policy for each operation from the provided config
+func NewRetryPolicies(cfg config.RetryConfig) map[Operation]backoff.Policy {
+	return map[Operation]backoff.Policy{
+		OpInstall:         NewRetryPolicy(cfg.Install),
+		OpUpgrade:         NewRetryPolicy(cfg.Upgrade),
+		OpUninstall:       NewRetryPolicy(cfg.Uninstall),
+		OpNamespaceDelete: NewRetryPolicy(cfg.NamespaceDelete),
+		OpMetadataUpdate:  NewRetryPolicy(cfg.MetadataUpdate),
+	}
+}
+
+// retryPolicy returns the policy for the operation. An operation without a policy is attempted once.
+func (s *Server) retryPolicy(op Operation) backoff.Policy {
+	if p, ok := s.RetryPolicies[op]; ok && p != nil {
+		return p
+	}
+
+	return backoff.Null()
+}
+
+// maxRedeliveryShift bounds the exponent of redelivery delays so they cannot overflow
+const maxRedeliveryShift = 30
+
+// redeliveryDelay returns how long a message waits before it is redelivered after a failed
+// loadbalancer lookup. The delay doubles with every delivery up to the max interval of the
+// policy, and false is returned once the message has been retried max retries times.
+func redeliveryDelay(cfg config.RetryPolicyConfig, deliveries uint64) (time.Duration, bool) {
+	if cfg.MaxRetries <= 0 || deliveries > uint64(cfg.MaxRetries) {
+		return 0, false
+	}
+
+	shift := deliveries - 1
+	if shift > maxRedeliveryShift {
+		shift = maxRedeliveryShift
+	}
+
+	delay := cfg.MinInterval << shift
+	if cfg.MaxInterval > 0 && delay > cfg.MaxInterval {
+		delay = cfg.MaxInterval
+	}
+
+	return delay, true
+}
+
+// retry runs fn until it succeeds, fails with a terminal error or the retry policy
+// for the operation is exhausted
+func (s *Server) retry(ctx context.Context, op Operation, fn func(context.Context) error) error {
+	var (
+		err      error
+		attempts int
+	)
+
+	b := s.retryPolicy(op).Start(ctx)
+	for backoff.Continue(b) {
+		if attempts > 0 {
+			operationRetriesCounter.WithLabelValues(string(op)).Inc()
+			s.Logger.Debugw("retrying operation", "operation", op, "attempt", attempts+1, "error", err)
+		}
+
+		attempts++
+
+		if err = fn(ctx); err == nil || !isRetryable(err) {
+			recordClusterOperation(ctx, op, err)
+			return err
+		}
+	}
+
+	recordClusterOperation(ctx, op, err)
+
+	if attempts == 0 {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// clusterOperations are the operations performed against a deployment cluster
+var clusterOperations = []Operation{OpInstall, OpUpgrade, OpUninstall, OpNamespaceDelete}
+
+// recordClusterOperation counts the result of an operation against the cluster targeted by the context
+func recordClusterOperation(ctx context.Context, op Operation, err error) {
+	if !slices.Contains(clusterOperations, op) {
+		return
+	}
+
+	clusterOperationsCounter.WithLabelValues(clusterName(ctx), string(op), resultLabel(err)).Inc()
+}
+
+// resultLabel is the metric label for the outcome of an operation
+func resultLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+
+	return "success"
+}
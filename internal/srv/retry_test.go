@@ -0,0 +1,212 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/stretchr/testify/assert"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+)
+
+func (suite *srvTestSuite) TestIsRetryable() { //nolint:govet
+	type testCase struct {
+		name   string
+		err    error
+		expect bool
+	}
+
+	testCases := []testCase{
+		{
+			name:   "no error",
+			err:    nil,
+			expect: false,
+		},
+		{
+			name:   "invalid object name length",
+			err:    errInvalidObjectNameLength,
+			expect: false,
+		},
+		{
+			name:   "wrapped invalid helm values",
+			err:    errors.Join(errors.New("bad values"), errInvalidHelmValues),
+			expect: false,
+		},
+		{
+			name:   "release not found",
+			err:    driver.ErrReleaseNotFound,
+			expect: false,
+		},
+		{
+			name:   "loadbalancer not found",
+			err:    lbapi.ErrLBNotfound,
+			expect: false,
+		},
+		{
+			name:   "kubernetes not found",
+			err:    apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "test"),
+			expect: false,
+		},
+		{
+			name:   "phase timeout",
+			err:    fmt.Errorf("%w: %s: %w", errPhaseTimeout, phaseAPILookup, context.DeadlineExceeded),
+			expect: true,
+		},
+		{
+			name:   "api internal server error",
+			err:    lbapi.ErrInternalServerError,
+			expect: true,
+		},
+		{
+			name:   "kubernetes server timeout",
+			err:    apierrors.NewServerTimeout(schema.GroupResource{Resource: "namespaces"}, "apply", 1),
+			expect: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, isRetryable(tc.err))
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestRetry() { //nolint:govet
+	errRetryable := errors.New("temporary failure")
+
+	policy := backoff.Constant(backoff.WithInterval(time.Millisecond), backoff.WithMaxRetries(3))
+
+	type testCase struct {
+		name           string
+		policies       map[Operation]backoff.Policy
+		err            error
+		succeedAfter   int
+		expectAttempts int
+		expectErr      error
+	}
+
+	testCases := []testCase{
+		{
+			name:           "succeeds first time",
+			policies:       map[Operation]backoff.Policy{OpInstall: policy},
+			expectAttempts: 1,
+		},
+		{
+			name:           "succeeds after retries",
+			policies:       map[Operation]backoff.Policy{OpInstall: policy},
+			err:            errRetryable,
+			succeedAfter:   2,
+			expectAttempts: 3,
+		},
+		{
+			name:           "retries exhausted",
+			policies:       map[Operation]backoff.Policy{OpInstall: policy},
+			err:            errRetryable,
+			succeedAfter:   10,
+			expectAttempts: 4,
+			expectErr:      errRetryable,
+		},
+		{
+			name:           "terminal error is not retried",
+			policies:       map[Operation]backoff.Policy{OpInstall: policy},
+			err:            errInvalidObjectNameLength,
+			succeedAfter:   10,
+			expectAttempts: 1,
+			expectErr:      errInvalidObjectNameLength,
+		},
+		{
+			name:           "no policy attempts once",
+			err:            errRetryable,
+			succeedAfter:   10,
+			expectAttempts: 1,
+			expectErr:      errRetryable,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			s := &Server{Logger: zap.NewNop().Sugar(), RetryPolicies: tc.policies}
+
+			attempts := 0
+			err := s.retry(context.TODO(), OpInstall, func(context.Context) error {
+				attempts++
+				if attempts <= tc.succeedAfter {
+					return tc.err
+				}
+
+				return nil
+			})
+
+			assert.Equal(t, tc.expectAttempts, attempts)
+
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, err, tc.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestNewRetryPolicy() { //nolint:govet
+	s := &Server{
+		Logger: zap.NewNop().Sugar(),
+		RetryPolicies: NewRetryPolicies(config.RetryConfig{
+			Uninstall: config.RetryPolicyConfig{MaxRetries: 2, MinInterval: time.Millisecond, MaxInterval: time.Millisecond},
+		}),
+	}
+
+	attempts := 0
+	_ = s.retry(context.TODO(), OpUninstall, func(context.Context) error {
+		attempts++
+		return errors.New("temporary failure")
+	})
+
+	assert.Equal(suite.T(), 3, attempts)
+
+	attempts = 0
+	_ = s.retry(context.TODO(), OpInstall, func(context.Context) error {
+		attempts++
+		return errors.New("temporary failure")
+	})
+
+	assert.Equal(suite.T(), 1, attempts)
+}
+
+func (suite *srvTestSuite) TestRedeliveryDelay() { //nolint:govet
+	cfg := config.RetryPolicyConfig{MaxRetries: 3, MinInterval: time.Second, MaxInterval: 3 * time.Second}
+
+	type testCase struct {
+		name          string
+		cfg           config.RetryPolicyConfig
+		deliveries    uint64
+		expectDelay   time.Duration
+		expectRetried bool
+	}
+
+	testCases := []testCase{
+		{name: "first delivery", cfg: cfg, deliveries: 1, expectDelay: time.Second, expectRetried: true},
+		{name: "delay doubles", cfg: cfg, deliveries: 2, expectDelay: 2 * time.Second, expectRetried: true},
+		{name: "delay is capped", cfg: cfg, deliveries: 3, expectDelay: 3 * time.Second, expectRetried: true},
+		{name: "retries exhausted", cfg: cfg, deliveries: 4},
+		{name: "no retries", deliveries: 1},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			delay, ok := redeliveryDelay(tc.cfg, tc.deliveries)
+
+			assert.Equal(t, tc.expectRetried, ok)
+			assert.Equal(t, tc.expectDelay, delay)
+		})
+	}
+}
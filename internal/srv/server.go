@@ -2,17 +2,24 @@ package srv
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/lestrrat-go/backoff/v2"
+	"github.com/prometheus/client_golang/prometheus"
 	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
 	metadata "go.infratographer.com/metadata-api/pkg/client"
+	"go.infratographer.com/x/echojwtx"
 	"go.infratographer.com/x/echox"
 	"go.infratographer.com/x/events"
 	"go.uber.org/zap"
 	"helm.sh/helm/v3/pkg/chart"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 
 	"go.infratographer.com/ipam-api/pkg/ipamclient"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
 )
 
 // instrumentationName is a unique package name used for tracing
@@ -20,43 +27,85 @@ const instrumentationName = "go.infratographer.com/load-balancer-operator/srv"
 
 // Server holds options for server connectivity and settings
 type Server struct {
-	APIClient        *lbapi.Client
-	BackoffConfig    backoff.Policy
-	IPAMClient       *ipamclient.Client
-	MetadataClient   *metadata.Client
-	Echo             *echox.Server
-	Context          context.Context
-	EventsConnection events.Connection
-	eventChannels    []<-chan events.Message[events.EventMessage]
-	changeChannels   []<-chan events.Message[events.ChangeMessage]
-	Logger           *zap.SugaredLogger
-	KubeClient       *rest.Config
-	Debug            bool
-	EventTopics      []string
-	ChangeTopics     []string
-	Chart            *chart.Chart
-	ChartPath        string
-	ValuesPath       string
-	Locations        []string
-	ServicePortKey   string
-	ContainerPortKey string
-	MetricsPort      int
-	LoadBalancers    map[string]*runner
+	APIClient          *lbapi.Client
+	RetryPolicies      map[Operation]backoff.Policy
+	LookupRetry        config.RetryPolicyConfig
+	Dedupe             *DedupeCache
+	Outbox             *StatusOutbox
+	EventRecorder      record.EventRecorder
+	Audit              *AuditLog
+	DeploymentStatus   bool
+	IPAMClient         *ipamclient.Client
+	MetadataClient     *metadata.Client
+	Echo               *echox.Server
+	AdminAuth          *echojwtx.Auth
+	AdminInsecure      bool
+	Context            context.Context
+	EventsConnection   events.Connection
+	DevPublisher       events.Publisher
+	eventChannels      []<-chan events.Message[events.EventMessage]
+	changeChannels     []<-chan events.Message[events.ChangeMessage]
+	Logger             *zap.SugaredLogger
+	LogLevels          *LogLevels
+	KubeClient         *rest.Config
+	SupergraphEndpoint string
+	Clusters           []*Cluster
+	Debug              bool
+	EventTopics        []string
+	ChangeTopics       []string
+	Chart              *chart.Chart
+	ChartPath          string
+	ValuesPath         string
+	Locations          []string
+	ServicePortKey     string
+	ContainerPortKey   string
+	MetricsPort        int
+	Timeouts           config.TimeoutConfig
+	Namespace          config.NamespaceConfig
+	LoadBalancers      map[string]*runner
+	InventoryInterval  time.Duration
+	inventory          *lbInventory
+	registryMu         sync.RWMutex
+	stuckNamespaces    *namespaceTracker
 }
 
 // Run will start the server queue connections and healthcheck endpoints
 func (s *Server) Run(ctx context.Context) error {
 	// TODO: load up the loadbalancers that this operator is responsible for
 	s.LoadBalancers = make(map[string]*runner)
+	s.inventory = newLBInventory()
+
+	if err := prometheus.Register(newInventoryCollector(s.inventory)); err != nil {
+		s.Logger.Warnw("unable to register managed loadbalancer collector", "error", err)
+	}
+
+	if s.InventoryInterval > 0 {
+		go s.runInventory(ctx, s.InventoryInterval)
+	}
+	s.stuckNamespaces = newNamespaceTracker()
+	go s.runNamespaceRecheck(ctx, namespaceRecheckInterval)
 
 	s.Echo.AddHandler(s)
 
+	if s.Outbox != nil {
+		go s.runOutbox(ctx)
+	}
+
+	checks := s.readinessChecks()
+	for name, check := range checks {
+		s.Echo.AddReadinessCheck(name, check)
+	}
+
 	go func() {
 		if err := s.Echo.Run(); err != nil {
 			s.Logger.Error("unable to start healthcheck server", zap.Error(err))
 		}
 	}()
 
+	if err := s.waitUntilReady(ctx, checks); err != nil {
+		return err
+	}
+
 	s.Logger.Infow("starting subscribers")
 
 	if err := s.configureSubscribers(ctx); err != nil {
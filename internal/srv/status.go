@@ -0,0 +1,94 @@
+package srv
+
+import (
+	"time"
+
+	lbmeta "go.infratographer.com/load-balancer-api/pkg/metadata"
+)
+
+const (
+	// loadBalancerStateDeleting is reported while a loadbalancer release and namespace are being removed
+	loadBalancerStateDeleting lbmeta.LoadBalancerState = "deleting"
+	// loadBalancerStateDegraded is reported when an update of a deployed loadbalancer fails.
+	// The previous release continues to serve traffic.
+	loadBalancerStateDegraded lbmeta.LoadBalancerState = "degraded"
+	// loadBalancerStateFailed is reported when a loadbalancer could not be deployed
+	loadBalancerStateFailed lbmeta.LoadBalancerState = "failed"
+)
+
+// reasons are machine readable explanations of why a loadbalancer changed state
+const (
+	statusReasonCreating         = "Creating"
+	statusReasonCreated          = "Created"
+	statusReasonCreateFailed     = "CreateFailed"
+	statusReasonUpdating         = "Updating"
+	statusReasonUpdated          = "Updated"
+	statusReasonUpdateFailed     = "UpdateFailed"
+	statusReasonAssigningIP      = "AssigningIPAddress"
+	statusReasonIPAssigned       = "IPAddressAssigned"
+	statusReasonIPAssignFailed   = "IPAddressAssignFailed"
+	statusReasonDeleting         = "Deleting"
+	statusReasonDeleted          = "Deleted"
+	statusReasonDeleteIncomplete = "DeleteIncomplete"
+	statusReasonPaused           = "Paused"
+	statusReasonResumed          = "Resumed"
+)
+
+// LoadBalancerStatus is the status of a loadbalancer written to the metadata service. The
+// state is stored alongside the details of the last operation so that users can see why a
+// loadbalancer is not working.
+type LoadBalancerStatus struct {
+	lbmeta.LoadBalancerStatus
+
+	PreviousState   lbmeta.LoadBalancerState `json:"previousState,omitempty"`
+	Reason          string                   `json:"reason,omitempty"`
+	Message         string                   `json:"message,omitempty"`
+	LastError       string                   `json:"lastError,omitempty"`
+	ChartVersion    string                   `json:"chartVersion,omitempty"`
+	ReleaseRevision int                      `json:"releaseRevision,omitempty"`
+	Paused          bool                     `json:"paused,omitempty"`
+	Timestamp       time.Time                `json:"timestamp"`
+}
+
+// newLoadBalancerStatus returns the status of the loadbalancer in the provided state
+func (s *Server) newLoadBalancerStatus(lb *loadBalancer, state lbmeta.LoadBalancerState, reason, message string, err error) *LoadBalancerStatus {
+	status := &LoadBalancerStatus{
+		LoadBalancerStatus: lbmeta.LoadBalancerStatus{State: state},
+		PreviousState:      lb.state,
+		Reason:             reason,
+		Message:            message,
+		ReleaseRevision:    lb.revision,
+		Timestamp:          time.Now().UTC(),
+	}
+
+	if err != nil {
+		status.LastError = err.Error()
+	}
+
+	if s.Chart != nil && s.Chart.Metadata != nil {
+		status.ChartVersion = s.Chart.Metadata.Version
+	}
+
+	return status
+}
+
+// reportStatus writes the state of the task loadbalancer to the metadata service. The
+// previous state is the last state reported by the task, or the state recorded by the
+// load-balancer-api when the task starts.
+func (t *lbTask) reportStatus(state lbmeta.LoadBalancerState, reason, message string, taskErr error) error {
+	if t.lb.state == "" {
+		if current := t.loadBalancerStatus(); current != nil {
+			t.lb.state = current.State
+		}
+	}
+
+	sts := t.srv.newLoadBalancerStatus(t.lb, state, reason, message, taskErr)
+	t.lb.state = state
+
+	if err := t.srv.LoadBalancerStatusUpdate(t.ctx, t.lb.loadBalancerID, sts); err != nil {
+		t.logger().Errorw("failed to update metadata", "error", err, "loadbalancerState", state)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lbmeta "go.infratographer.com/load-balancer-api/pkg/metadata"
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func (suite *srvTestSuite) TestNewLoadBalancerStatus() { //nolint:govet
+	srv := Server{Chart: &chart.Chart{Metadata: &chart.Metadata{Version: "1.2.3"}}}
+	lb := &loadBalancer{loadBalancerID: gidx.MustNewID(LBPrefix), revision: 4}
+
+	sts := srv.newLoadBalancerStatus(lb, loadBalancerStateDegraded, statusReasonUpdateFailed, "unable to update loadbalancer", errors.New("upgrade failed")) //nolint:goerr113
+
+	assert.Equal(suite.T(), loadBalancerStateDegraded, sts.State)
+	assert.Equal(suite.T(), "upgrade failed", sts.LastError)
+	assert.Equal(suite.T(), "1.2.3", sts.ChartVersion)
+	assert.Equal(suite.T(), 4, sts.ReleaseRevision)
+	assert.False(suite.T(), sts.Timestamp.IsZero())
+
+	data, err := json.Marshal(sts)
+	require.NoError(suite.T(), err)
+
+	// the load-balancer-api reads the state from the same document
+	var apiStatus lbmeta.LoadBalancerStatus
+
+	require.NoError(suite.T(), json.Unmarshal(data, &apiStatus))
+	assert.Equal(suite.T(), loadBalancerStateDegraded, apiStatus.State)
+	assert.Contains(suite.T(), string(data), `"message":"unable to update loadbalancer"`)
+	assert.Contains(suite.T(), string(data), `"releaseRevision":4`)
+}
+
+// publishedEvents records the events published through a connection
+type publishedEvents struct {
+	events.Connection
+
+	subjects []string
+	messages []events.EventMessage
+}
+
+func (p *publishedEvents) PublishEvent(_ context.Context, topic string, msg events.EventMessage) (events.Message[events.EventMessage], error) {
+	p.subjects = append(p.subjects, topic)
+	p.messages = append(p.messages, msg)
+
+	return nil, nil
+}
+
+func (suite *srvTestSuite) TestReportStatusPublishesTransitions() { //nolint:govet
+	conn := &publishedEvents{}
+	id := gidx.MustNewID(LBPrefix)
+
+	task := &lbTask{
+		lb:  &loadBalancer{loadBalancerID: id, lbType: typeLB},
+		ctx: context.TODO(),
+		srv: &Server{Logger: zap.NewNop().Sugar(), EventsConnection: conn},
+	}
+
+	require.NoError(suite.T(), task.reportStatus(lbmeta.LoadBalancerStateUpdating, statusReasonUpdating, "updating loadbalancer", nil))
+	require.NoError(suite.T(), task.reportStatus(loadBalancerStateDegraded, statusReasonUpdateFailed, "unable to update loadbalancer", errors.New("upgrade failed"))) //nolint:goerr113
+
+	assert.Equal(suite.T(), []string{"load-balancer.updating", "load-balancer.degraded"}, conn.subjects)
+
+	first, second := conn.messages[0], conn.messages[1]
+
+	assert.Equal(suite.T(), id, first.SubjectID)
+	assert.NotContains(suite.T(), first.Data, "previousState")
+	assert.Equal(suite.T(), "updating", first.Data["state"])
+	assert.Equal(suite.T(), statusReasonUpdating, first.Data["reason"])
+
+	assert.Equal(suite.T(), "updating", second.Data["previousState"])
+	assert.Equal(suite.T(), "degraded", second.Data["state"])
+	assert.Equal(suite.T(), statusReasonUpdateFailed, second.Data["reason"])
+	assert.Equal(suite.T(), "upgrade failed", second.Data["lastError"])
+}
@@ -2,26 +2,90 @@ package srv
 
 import (
 	"context"
+	"sync"
+	"time"
+
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
 )
 
 // runner is a struct that manages the flow of messages for a given loadbalancer
 // separate reader and writer channels are utilized in conjunction with a buffer
 // to ensure that messages are processed as they are received without blocking
 // while the buffer is utilized in order to ensure that only a single message for
-// a given loadbalancer is processed at one time
+// a given loadbalancer is processed at one time. Deletes take priority over any
+// other queued work for the loadbalancer.
 type runner struct {
 	reader     chan *lbTask
 	writer     chan *lbTask
 	quit       chan struct{}
+	done       chan struct{}
+	wake       chan struct{}
 	buffer     []*lbTask
 	taskRunner func(*lbTask)
+
+	mu        sync.Mutex
+	current   *lbTask
+	lb        *loadBalancer
+	queued    int
+	started   time.Time
+	lastEvent string
+	lastError string
+	lastRun   time.Time
+	revision  int
+	state     string
+	pause     pauseMode
+
+	// generation counts the loadbalancers handed to the runner, it changes whenever the
+	// runner is given new work
+	generation uint64
 }
 
 type lbTask struct {
-	lb  *loadBalancer
-	ctx context.Context
-	evt string
-	srv *Server
+	lb     *loadBalancer
+	ctx    context.Context
+	cancel context.CancelFunc
+	evt    string
+	subj   gidx.PrefixedID
+	srv    *Server
+	err    error
+
+	// dedupeKey is released when the task fails so that the message can be processed again
+	dedupeKey string
+}
+
+// newTask returns a task with its own cancelable context so that it can be
+// superseded by a delete for the same loadbalancer
+func newTask(ctx context.Context, s *Server, lb *loadBalancer, evt string, subj gidx.PrefixedID) *lbTask {
+	ctx, cancel := context.WithCancel(ctx)
+
+	if s != nil && lb != nil {
+		ctx = withLogger(ctx, s.taskLogger(ctx, lb, evt))
+	}
+
+	return &lbTask{lb: lb, ctx: ctx, cancel: cancel, evt: evt, subj: subj, srv: s}
+}
+
+// logger returns the logger of the task
+func (t *lbTask) logger() *zap.SugaredLogger {
+	if l, ok := t.ctx.Value(loggerKey{}).(*zap.SugaredLogger); ok {
+		return l
+	}
+
+	return t.srv.taskLogger(t.ctx, t.lb, t.evt)
+}
+
+// isDelete reports whether the task removes the loadbalancer
+func (t *lbTask) isDelete() bool {
+	return t.evt == string(events.DeleteChangeType) && t.subj.Prefix() == LBPrefix
+}
+
+// cancelTask cancels the task context, aborting any in-flight operations
+func (t *lbTask) cancelTask() {
+	if t.cancel != nil {
+		t.cancel()
+	}
 }
 
 type taskRunner func(*lbTask)
@@ -36,6 +100,13 @@ func (r *runner) stop() {
 // if a message is received on the writer channel it is added to the buffer
 // if a message is received on the reader channel, a message is removed from the buffer and passed to the taskRunner function
 func (r *runner) run() {
+	defer close(r.done)
+	defer close(r.reader)
+	defer func() {
+		activeRunnersGauge.Dec()
+		r.setBuffer(nil)
+	}()
+
 	go r.listen()
 
 	for {
@@ -43,37 +114,198 @@ func (r *runner) run() {
 		case <-r.quit:
 			return
 		default:
-			if len(r.buffer) > 0 {
+			if len(r.buffer) > 0 && r.pauseMode() == pauseNone {
 				select {
+				case <-r.quit:
+					return
 				case r.reader <- r.buffer[0]:
-					r.buffer = r.buffer[1:]
+					r.setBuffer(r.buffer[1:])
 				case d := <-r.writer:
-					r.buffer = append(r.buffer, d)
+					r.enqueue(d)
+				case <-r.wake:
 				}
 			} else {
-				d := <-r.writer
-				r.buffer = append(r.buffer, d)
+				select {
+				case <-r.quit:
+					return
+				case d := <-r.writer:
+					r.enqueue(d)
+				case <-r.wake:
+				}
 			}
 		}
 	}
 }
 
+// submit hands a task to the runner. False is returned if the runner has stopped.
+func (r *runner) submit(t *lbTask) bool {
+	select {
+	case r.writer <- t:
+		return true
+	case <-r.done:
+		return false
+	}
+}
+
+// enqueue adds a task to the buffer. A delete jumps the queue: queued tasks are
+// dropped and the in-flight task is canceled, as they would only run against a
+// loadbalancer that is being removed.
+func (r *runner) enqueue(t *lbTask) {
+	if !t.isDelete() && r.pauseMode() == pauseSkip {
+		t.cancelTask()
+		pausedTasksSkippedCounter.Inc()
+
+		return
+	}
+
+	if !t.isDelete() {
+		r.setBuffer(append(r.buffer, t))
+
+		return
+	}
+
+	for _, queued := range r.buffer {
+		queued.cancelTask()
+		supersededTasksCounter.Inc()
+	}
+
+	r.setBuffer([]*lbTask{t})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.current != nil && !r.current.isDelete() {
+		r.current.cancelTask()
+		supersededTasksCounter.Inc()
+	}
+}
+
 // listen pulls messages from the buffer and passes them to the taskRunner function
 func (r *runner) listen() {
 	for d := range r.reader {
+		r.mu.Lock()
+		r.current = d
+		r.started = time.Now().UTC()
+		r.mu.Unlock()
+
 		r.taskRunner(d)
+
+		r.finished(d)
+
+		d.cancelTask()
+	}
+}
+
+// pauseMode describes how a paused runner treats new tasks
+type pauseMode string
+
+const (
+	// pauseNone is a runner that is processing tasks
+	pauseNone pauseMode = ""
+	// pauseBuffer holds new tasks until the runner is resumed
+	pauseBuffer pauseMode = "buffer"
+	// pauseSkip drops new tasks until the runner is resumed. Deletes are always held.
+	pauseSkip pauseMode = "skip"
+)
+
+// setPause pauses the runner in the provided mode, or resumes it with pauseNone. Tasks
+// already running are not interrupted.
+func (r *runner) setPause(mode pauseMode) {
+	r.mu.Lock()
+	r.pause = mode
+	r.mu.Unlock()
+
+	// wake the run loop so that it picks up the change
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *runner) pauseMode() pauseMode {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.pause
+}
+
+// setBuffer replaces the queued tasks
+func (r *runner) setBuffer(buffer []*lbTask) {
+	runnerQueueDepthGauge.Add(float64(len(buffer) - len(r.buffer)))
+	r.buffer = buffer
+
+	r.mu.Lock()
+	r.queued = len(buffer)
+	r.mu.Unlock()
+}
+
+// finished records the outcome of the task the runner has completed
+func (r *runner) finished(t *lbTask) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.current = nil
+	r.lastEvent = t.evt
+	r.lastRun = time.Now().UTC()
+	r.lastError = ""
+
+	if t.err != nil {
+		r.lastError = t.err.Error()
+	}
+
+	if t.lb != nil {
+		if t.lb.revision != 0 {
+			r.revision = t.lb.revision
+		}
+
+		if t.lb.state != "" {
+			r.state = string(t.lb.state)
+		}
 	}
 }
 
+// track records the loadbalancer most recently handed to the runner. Deletes do not
+// carry loadbalancer data so they do not replace a loadbalancer that has it.
+func (r *runner) track(lb *loadBalancer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+
+	if r.lb == nil || lb.lbData != nil {
+		r.lb = lb
+	}
+}
+
+// currentGeneration returns the number of loadbalancers handed to the runner
+func (r *runner) currentGeneration() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.generation
+}
+
+// loadBalancer returns the loadbalancer most recently handed to the runner
+func (r *runner) loadBalancer() *loadBalancer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.lb
+}
+
 func NewRunner(ctx context.Context, tr taskRunner) *runner {
 	r := &runner{
 		reader:     make(chan *lbTask),
 		writer:     make(chan *lbTask),
 		buffer:     make([]*lbTask, 0),
 		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+		wake:       make(chan struct{}, 1),
 		taskRunner: tr,
 	}
 
+	activeRunnersGauge.Inc()
+
 	go r.run()
 
 	return r
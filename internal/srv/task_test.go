@@ -0,0 +1,132 @@
+package srv
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+)
+
+func (suite *srvTestSuite) TestRunnerDeletePriority() { //nolint:govet
+	id := gidx.MustNewID(LBPrefix)
+	lb := &loadBalancer{loadBalancerID: id, lbType: typeLB}
+	started := make(chan *lbTask, 10)
+
+	r := NewRunner(context.TODO(), func(t *lbTask) {
+		started <- t
+
+		if !t.isDelete() {
+			// simulate a long running helm operation that honors cancellation
+			<-t.ctx.Done()
+		}
+	})
+
+	inflight := newTask(context.TODO(), nil, lb, string(events.UpdateChangeType), id)
+	queued := newTask(context.TODO(), nil, lb, string(events.UpdateChangeType), id)
+	del := newTask(context.TODO(), nil, lb, string(events.DeleteChangeType), id)
+
+	require.True(suite.T(), r.submit(inflight))
+	assert.Equal(suite.T(), inflight, <-started)
+
+	require.True(suite.T(), r.submit(queued))
+	require.True(suite.T(), r.submit(del))
+
+	select {
+	case next := <-started:
+		assert.Equal(suite.T(), del, next, "delete should run before queued updates")
+	case <-time.After(5 * time.Second):
+		suite.T().Fatal("delete was not processed")
+	}
+
+	assert.ErrorIs(suite.T(), inflight.ctx.Err(), context.Canceled)
+	assert.ErrorIs(suite.T(), queued.ctx.Err(), context.Canceled)
+
+	r.stop()
+
+	assert.False(suite.T(), r.submit(newTask(context.TODO(), nil, lb, string(events.UpdateChangeType), id)))
+	assert.Empty(suite.T(), started)
+}
+
+func (suite *srvTestSuite) TestRunnerMetrics() { //nolint:govet
+	id := gidx.MustNewID(LBPrefix)
+	lb := &loadBalancer{loadBalancerID: id, lbType: typeLB}
+	release := make(chan struct{})
+
+	runners := testutil.ToFloat64(activeRunnersGauge)
+	depth := testutil.ToFloat64(runnerQueueDepthGauge)
+
+	r := NewRunner(context.TODO(), func(t *lbTask) {
+		<-release
+	})
+
+	assert.Equal(suite.T(), runners+1, testutil.ToFloat64(activeRunnersGauge))
+
+	for i := 0; i < 3; i++ {
+		require.True(suite.T(), r.submit(newTask(context.TODO(), nil, lb, string(events.UpdateChangeType), id)))
+	}
+
+	// one task is running and the rest are queued
+	assert.Eventually(suite.T(), func() bool {
+		return testutil.ToFloat64(runnerQueueDepthGauge) == depth+2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// a delete replaces the queued tasks
+	require.True(suite.T(), r.submit(newTask(context.TODO(), nil, lb, string(events.DeleteChangeType), id)))
+	assert.Eventually(suite.T(), func() bool {
+		return testutil.ToFloat64(runnerQueueDepthGauge) == depth+1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(release)
+	r.stop()
+	<-r.done
+
+	assert.Equal(suite.T(), runners, testutil.ToFloat64(activeRunnersGauge))
+	assert.Equal(suite.T(), depth, testutil.ToFloat64(runnerQueueDepthGauge))
+}
+
+func (suite *srvTestSuite) TestRunnerPause() { //nolint:govet
+	id := gidx.MustNewID(LBPrefix)
+	lb := &loadBalancer{loadBalancerID: id, lbType: typeLB}
+	started := make(chan *lbTask, 10)
+
+	r := NewRunner(context.TODO(), func(t *lbTask) {
+		started <- t
+	})
+	defer r.stop()
+
+	r.setPause(pauseBuffer)
+
+	held := newTask(context.TODO(), nil, lb, string(events.UpdateChangeType), id)
+	require.True(suite.T(), r.submit(held))
+
+	assert.Never(suite.T(), func() bool { return len(started) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	assert.Equal(suite.T(), 1, r.summary(id.String()).QueueLength)
+
+	// skipped tasks are dropped while deletes are still held
+	r.setPause(pauseSkip)
+
+	skipped := newTask(context.TODO(), nil, lb, string(events.UpdateChangeType), id)
+	del := newTask(context.TODO(), nil, lb, string(events.DeleteChangeType), id)
+
+	require.True(suite.T(), r.submit(skipped))
+	require.True(suite.T(), r.submit(del))
+
+	assert.Eventually(suite.T(), func() bool { return skipped.ctx.Err() != nil }, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(suite.T(), started)
+
+	r.setPause(pauseNone)
+
+	select {
+	case next := <-started:
+		assert.Equal(suite.T(), del, next)
+	case <-time.After(5 * time.Second):
+		suite.T().Fatal("held delete was not processed after resume")
+	}
+
+	// the delete superseded the held update
+	assert.ErrorIs(suite.T(), held.ctx.Err(), context.Canceled)
+}
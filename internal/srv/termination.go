@@ -0,0 +1,268 @@
+package srv
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	lbmeta "go.infratographer.com/load-balancer-api/pkg/metadata"
+	"go.infratographer.com/x/gidx"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// namespaceTerminationPollInterval is how often a deleted namespace is checked while waiting for it to terminate
+	namespaceTerminationPollInterval = 2 * time.Second
+	// namespaceRecheckInterval is how often namespaces recorded as stuck are checked again
+	namespaceRecheckInterval = 30 * time.Second
+)
+
+// terminatingNamespace describes a deleted namespace that did not terminate in time
+type terminatingNamespace struct {
+	Namespace         string     `json:"namespace"`
+	LoadBalancerID    string     `json:"loadBalancerID,omitempty"`
+	DeletionTimestamp *time.Time `json:"deletionTimestamp,omitempty"`
+	Finalizers        []string   `json:"finalizers,omitempty"`
+	Conditions        []string   `json:"conditions,omitempty"`
+	LastChecked       time.Time  `json:"lastChecked"`
+
+	// cluster is the cluster the namespace was deleted from, nil for the default cluster
+	cluster *Cluster
+
+	// runner is the runner that deleted the namespace and generation its generation at
+	// the time, the runner is only stopped if it has not been given new work since
+	runner     *runner
+	generation uint64
+}
+
+// namespaceTracker records namespaces that are stuck terminating
+type namespaceTracker struct {
+	mu    sync.Mutex
+	stuck map[string]terminatingNamespace
+}
+
+func newNamespaceTracker() *namespaceTracker {
+	return &namespaceTracker{
+		stuck: make(map[string]terminatingNamespace),
+	}
+}
+
+func (t *namespaceTracker) add(ns terminatingNamespace) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stuck[ns.Namespace] = ns
+	stuckNamespacesGauge.Set(float64(len(t.stuck)))
+}
+
+func (t *namespaceTracker) remove(name string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.stuck, name)
+	stuckNamespacesGauge.Set(float64(len(t.stuck)))
+}
+
+// list returns the stuck namespaces ordered by name
+func (t *namespaceTracker) list() []terminatingNamespace {
+	if t == nil {
+		return []terminatingNamespace{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]terminatingNamespace, 0, len(t.stuck))
+	for _, ns := range t.stuck {
+		out = append(out, ns)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Namespace < out[j].Namespace })
+
+	return out
+}
+
+// waitForNamespaceTermination waits until a deleted namespace no longer exists. Namespaces
+// that are still terminating once the timeout passes, usually because of a finalizer, are
+// recorded as stuck and errNamespaceStuck is returned.
+func (s *Server) waitForNamespaceTermination(ctx context.Context, client kubernetes.Interface, name string) error {
+	timeout := s.Timeouts.NamespaceTermination
+	if timeout <= 0 {
+		return nil
+	}
+
+	start := time.Now()
+
+	var last *v1.Namespace
+
+	err := wait.PollUntilContextTimeout(ctx, namespaceTerminationPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		ns, err := client.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+
+		switch {
+		case apierrors.IsNotFound(err):
+			return true, nil
+		case err != nil:
+			s.logger(ctx).Debugw("unable to check namespace termination", "error", err, "namespace", name)
+			return false, nil
+		}
+
+		last = ns
+
+		return false, nil
+	})
+	if err == nil {
+		namespaceTerminationDuration.Observe(time.Since(start).Seconds())
+		s.stuckNamespaces.remove(name)
+
+		return nil
+	}
+
+	// the task was canceled, the namespace is not known to be stuck
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	stuck := newTerminatingNamespace(name, last)
+	stuck.cluster = clusterFromContext(ctx)
+
+	if r, ok := s.runners()[stuck.LoadBalancerID]; ok {
+		stuck.runner, stuck.generation = r, r.currentGeneration()
+	}
+
+	s.stuckNamespaces.add(stuck)
+
+	s.logger(ctx).Warnw("namespace stuck terminating", "namespace", name, "loadBalancer", stuck.LoadBalancerID, "finalizers", stuck.Finalizers, "conditions", stuck.Conditions, "timeout", timeout)
+
+	return fmt.Errorf("%w: %s did not terminate within %s", errNamespaceStuck, name, timeout)
+}
+
+// runNamespaceRecheck checks the stuck namespaces again every interval until ctx is
+// canceled, finishing the delete of their loadbalancers once they are gone
+func (s *Server) runNamespaceRecheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, ns := range s.stuckNamespaces.list() {
+			ctx := withCluster(ctx, ns.cluster)
+
+			kc, err := kubernetes.NewForConfig(s.restConfig(ctx))
+			if err != nil {
+				s.logger(ctx).Debugw("unable to authenticate against kubernetes cluster", "error", err)
+				continue
+			}
+
+			s.recheckNamespace(ctx, kc, ns)
+		}
+	}
+}
+
+// recheckNamespace checks whether a stuck namespace has terminated. The loadbalancer is
+// reported deleted and its runner stopped once the namespace is gone, otherwise the
+// recorded namespace details are refreshed.
+func (s *Server) recheckNamespace(ctx context.Context, client kubernetes.Interface, stuck terminatingNamespace) {
+	ns, err := client.CoreV1().Namespaces().Get(ctx, stuck.Namespace, metav1.GetOptions{})
+
+	switch {
+	case apierrors.IsNotFound(err):
+		s.finishNamespaceDelete(ctx, stuck)
+	case err != nil:
+		s.logger(ctx).Debugw("unable to check namespace termination", "error", err, "namespace", stuck.Namespace)
+	default:
+		refreshed := newTerminatingNamespace(stuck.Namespace, ns)
+		refreshed.cluster = stuck.cluster
+		refreshed.runner, refreshed.generation = stuck.runner, stuck.generation
+
+		if refreshed.LoadBalancerID == "" {
+			refreshed.LoadBalancerID = stuck.LoadBalancerID
+		}
+
+		s.stuckNamespaces.add(refreshed)
+	}
+}
+
+// finishNamespaceDelete completes the delete of the loadbalancer of a stuck namespace that
+// has terminated. The namespace stays recorded as stuck when the status cannot be reported
+// so that the next check tries again. Nothing is done for a loadbalancer that has been
+// given new work since the delete, the runner is left to process it.
+func (s *Server) finishNamespaceDelete(ctx context.Context, stuck terminatingNamespace) {
+	s.logger(ctx).Infow("stuck namespace terminated", "namespace", stuck.Namespace, "loadBalancer", stuck.LoadBalancerID)
+
+	id, err := gidx.Parse(stuck.LoadBalancerID)
+	if err != nil {
+		s.stuckNamespaces.remove(stuck.Namespace)
+		return
+	}
+
+	if r, ok := s.runners()[id.String()]; ok && (r != stuck.runner || r.currentGeneration() != stuck.generation) {
+		s.logger(ctx).Infow("loadbalancer has new work since the delete, leaving its runner running", "namespace", stuck.Namespace, "loadBalancer", stuck.LoadBalancerID)
+		s.stuckNamespaces.remove(stuck.Namespace)
+
+		return
+	}
+
+	lb := &loadBalancer{loadBalancerID: id, lbType: typeLB, state: loadBalancerStateDeleting}
+	status := s.newLoadBalancerStatus(lb, lbmeta.LoadBalancerStateDeleted, statusReasonDeleted, "loadbalancer deleted", nil)
+
+	if err := s.LoadBalancerStatusUpdate(ctx, id, status); err != nil {
+		s.logger(ctx).Errorw("failed to update metadata", "error", err, "loadBalancer", stuck.LoadBalancerID)
+		return
+	}
+
+	if stuck.runner != nil && s.removeIdleRunner(id.String(), stuck.runner, stuck.generation) {
+		stuck.runner.stop()
+	}
+
+	s.stuckNamespaces.remove(stuck.Namespace)
+}
+
+func newTerminatingNamespace(name string, ns *v1.Namespace) terminatingNamespace {
+	stuck := terminatingNamespace{
+		Namespace:   name,
+		LastChecked: time.Now().UTC(),
+	}
+
+	if ns == nil {
+		return stuck
+	}
+
+	stuck.LoadBalancerID = ns.Annotations[lbIDAnnotation]
+	stuck.Finalizers = append(stuck.Finalizers, ns.Finalizers...)
+
+	for _, f := range ns.Spec.Finalizers {
+		stuck.Finalizers = append(stuck.Finalizers, string(f))
+	}
+
+	if ns.DeletionTimestamp != nil {
+		ts := ns.DeletionTimestamp.UTC()
+		stuck.DeletionTimestamp = &ts
+	}
+
+	for _, c := range ns.Status.Conditions {
+		if c.Status == v1.ConditionTrue {
+			stuck.Conditions = append(stuck.Conditions, fmt.Sprintf("%s: %s", c.Type, c.Message))
+		}
+	}
+
+	return stuck
+}
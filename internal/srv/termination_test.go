@@ -0,0 +1,192 @@
+package srv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/echox"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+)
+
+func (suite *srvTestSuite) TestWaitForNamespaceTermination() { //nolint:govet
+	type testCase struct {
+		name        string
+		timeout     time.Duration
+		namespace   *v1.Namespace
+		expectStuck bool
+	}
+
+	deleted := metav1.NewTime(time.Now())
+
+	testCases := []testCase{
+		{
+			name:    "namespace removed",
+			timeout: time.Second,
+		},
+		{
+			name:    "namespace stuck terminating",
+			timeout: 100 * time.Millisecond,
+			namespace: &v1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "lb-stuck",
+					Annotations:       map[string]string{lbIDAnnotation: "loadbal-stuck"},
+					DeletionTimestamp: &deleted,
+					Finalizers:        []string{"example.com/finalizer"},
+				},
+				Status: v1.NamespaceStatus{Phase: v1.NamespaceTerminating},
+			},
+			expectStuck: true,
+		},
+		{
+			name: "waiting disabled",
+			namespace: &v1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "lb-stuck"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if tc.namespace != nil {
+				client = fake.NewSimpleClientset(tc.namespace)
+			}
+
+			srv := Server{
+				Logger:          zap.NewNop().Sugar(),
+				Timeouts:        config.TimeoutConfig{NamespaceTermination: tc.timeout},
+				stuckNamespaces: newNamespaceTracker(),
+			}
+
+			err := srv.waitForNamespaceTermination(context.TODO(), client, "lb-stuck")
+
+			if !tc.expectStuck {
+				assert.NoError(t, err)
+				assert.Empty(t, srv.stuckNamespaces.list())
+
+				return
+			}
+
+			assert.ErrorIs(t, err, errNamespaceStuck)
+
+			stuck := srv.stuckNamespaces.list()
+			require.Len(t, stuck, 1)
+			assert.Equal(t, "lb-stuck", stuck[0].Namespace)
+			assert.Equal(t, "loadbal-stuck", stuck[0].LoadBalancerID)
+			assert.Equal(t, []string{"example.com/finalizer"}, stuck[0].Finalizers)
+			assert.NotNil(t, stuck[0].DeletionTimestamp)
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestRecheckNamespace() { //nolint:govet
+	type testCase struct {
+		name          string
+		namespace     *v1.Namespace
+		newWork       bool
+		expectDeleted bool
+	}
+
+	testCases := []testCase{
+		{
+			name:          "namespace terminated",
+			expectDeleted: true,
+		},
+		{
+			name:    "namespace terminated after new work",
+			newWork: true,
+		},
+		{
+			name: "namespace still terminating",
+			namespace: &v1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "lb-stuck",
+					Finalizers: []string{"example.com/finalizer"},
+				},
+				Status: v1.NamespaceStatus{Phase: v1.NamespaceTerminating},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if tc.namespace != nil {
+				client = fake.NewSimpleClientset(tc.namespace)
+			}
+
+			id := gidx.MustNewID(LBPrefix)
+			r := NewRunner(context.TODO(), process)
+
+			srv := Server{
+				Context:         context.TODO(),
+				Logger:          zap.NewNop().Sugar(),
+				LoadBalancers:   map[string]*runner{id.String(): r},
+				stuckNamespaces: newNamespaceTracker(),
+			}
+
+			srv.stuckNamespaces.add(terminatingNamespace{Namespace: "lb-stuck", LoadBalancerID: id.String(), runner: r, generation: r.currentGeneration()})
+
+			if tc.newWork {
+				r.track(&loadBalancer{loadBalancerID: id})
+			}
+
+			srv.recheckNamespace(context.TODO(), client, srv.stuckNamespaces.list()[0])
+
+			if tc.expectDeleted {
+				assert.Empty(t, srv.stuckNamespaces.list())
+				assert.NotContains(t, srv.runners(), id.String())
+
+				return
+			}
+
+			if tc.newWork {
+				assert.Empty(t, srv.stuckNamespaces.list())
+				assert.Contains(t, srv.runners(), id.String())
+
+				r.stop()
+
+				return
+			}
+
+			stuck := srv.stuckNamespaces.list()
+			require.Len(t, stuck, 1)
+			assert.Equal(t, id.String(), stuck[0].LoadBalancerID)
+			assert.Equal(t, []string{"example.com/finalizer"}, stuck[0].Finalizers)
+			assert.Contains(t, srv.runners(), id.String())
+		})
+	}
+}
+
+func (suite *srvTestSuite) TestTerminatingNamespacesHandler() { //nolint:govet
+	e, err := echox.NewServer(zap.NewNop(), echox.Config{}, nil)
+	require.NoError(suite.T(), err, "unexpected error creating new server")
+
+	s := &Server{
+		Echo:            e,
+		AdminInsecure:   true,
+		stuckNamespaces: newNamespaceTracker(),
+	}
+
+	s.stuckNamespaces.add(terminatingNamespace{Namespace: "lb-stuck", LoadBalancerID: "loadbal-stuck"})
+	s.Echo.AddHandler(s)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/namespaces/terminating", nil)
+	rec := httptest.NewRecorder()
+	s.Echo.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Contains(suite.T(), rec.Body.String(), `"namespace":"lb-stuck"`)
+	assert.Contains(suite.T(), rec.Body.String(), `"loadBalancerID":"loadbal-stuck"`)
+}
@@ -0,0 +1,57 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// phase identifies a stage of processing a loadbalancer that is bounded by a timeout
+type phase string
+
+const (
+	phaseAPILookup phase = "api-lookup"
+	phaseNamespace phase = "namespace"
+	phaseHelm      phase = "helm"
+	phaseMetadata  phase = "metadata"
+)
+
+// phaseTimeout returns the configured timeout for the phase
+func (s *Server) phaseTimeout(p phase) time.Duration {
+	switch p {
+	case phaseAPILookup:
+		return s.Timeouts.APILookup
+	case phaseNamespace:
+		return s.Timeouts.Namespace
+	case phaseHelm:
+		return s.Timeouts.Helm
+	case phaseMetadata:
+		return s.Timeouts.Metadata
+	default:
+		return 0
+	}
+}
+
+// withPhaseTimeout runs fn with a context bounded by the timeout configured for the phase.
+// If the phase runs out of time the returned error wraps errPhaseTimeout.
+func (s *Server) withPhaseTimeout(ctx context.Context, p phase, fn func(context.Context) error) error {
+	timeout := s.phaseTimeout(p)
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := fn(pctx)
+
+	// only report the timeout when it was this phase that ran out of time and not the task itself
+	if err != nil && errors.Is(pctx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		phaseTimeoutsCounter.WithLabelValues(string(p)).Inc()
+
+		return fmt.Errorf("%w: %s did not complete within %s: %w", errPhaseTimeout, p, timeout, err)
+	}
+
+	return err
+}
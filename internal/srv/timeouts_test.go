@@ -0,0 +1,80 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.infratographer.com/load-balancer-operator/internal/config"
+)
+
+func (suite *srvTestSuite) TestWithPhaseTimeout() { //nolint:govet
+	errTest := errors.New("test error")
+
+	blocking := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	type testCase struct {
+		name          string
+		timeouts      config.TimeoutConfig
+		cancelParent  bool
+		fn            func(context.Context) error
+		expectErr     error
+		expectTimeout bool
+	}
+
+	testCases := []testCase{
+		{
+			name:      "no timeout configured",
+			fn:        func(context.Context) error { return errTest },
+			expectErr: errTest,
+		},
+		{
+			name:     "completes within timeout",
+			timeouts: config.TimeoutConfig{Helm: time.Second},
+			fn:       func(context.Context) error { return nil },
+		},
+		{
+			name:          "exceeds timeout",
+			timeouts:      config.TimeoutConfig{Helm: 10 * time.Millisecond},
+			fn:            blocking,
+			expectErr:     context.DeadlineExceeded,
+			expectTimeout: true,
+		},
+		{
+			name:         "task canceled",
+			timeouts:     config.TimeoutConfig{Helm: time.Second},
+			cancelParent: true,
+			fn:           blocking,
+			expectErr:    context.Canceled,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			s := &Server{Timeouts: tc.timeouts}
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			if tc.cancelParent {
+				cancel()
+			}
+
+			err := s.withPhaseTimeout(ctx, phaseHelm, tc.fn)
+
+			if tc.expectErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.expectErr)
+			}
+
+			assert.Equal(t, tc.expectTimeout, errors.Is(err, errPhaseTimeout))
+		})
+	}
+}
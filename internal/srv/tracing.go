@@ -0,0 +1,51 @@
+package srv
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
+)
+
+// InstrumentConfig returns a copy of cfg whose requests to the kubernetes API are traced
+// as children of the span in the request context
+func InstrumentConfig(cfg *rest.Config) *rest.Config {
+	if cfg == nil {
+		return nil
+	}
+
+	cfg = rest.CopyConfig(cfg)
+	cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return otelhttp.NewTransport(rt, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "kubernetes " + r.Method
+		}))
+	})
+
+	return cfg
+}
+
+// withSpan runs fn in a child span of ctx, recording the error it returns on the span
+func withSpan(ctx context.Context, name string, fn func(context.Context) error, attrs ...attribute.KeyValue) error {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+	defer span.End()
+
+	err := fn(ctx)
+	recordSpanError(span, err)
+
+	return err
+}
+
+// recordSpanError marks the span as failed when err is set
+func recordSpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,58 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func (suite *srvTestSuite) TestTracing() { //nolint:govet
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+
+	defer otel.SetTracerProvider(previous)
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer api.Close()
+
+	kc, err := kubernetes.NewForConfig(InstrumentConfig(&rest.Config{Host: api.URL}))
+	require.NoError(suite.T(), err)
+
+	errApply := errors.New("apply failed") //nolint:goerr113
+
+	err = withSpan(context.TODO(), "kubernetes.namespace.apply", func(ctx context.Context) error {
+		_, err := kc.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+		require.NoError(suite.T(), err)
+
+		return errApply
+	})
+	assert.ErrorIs(suite.T(), err, errApply)
+
+	spans := recorder.Ended()
+	require.Len(suite.T(), spans, 2)
+
+	request, apply := spans[0], spans[1]
+
+	assert.Equal(suite.T(), "kubernetes GET", request.Name())
+	assert.Equal(suite.T(), apply.SpanContext().SpanID(), request.Parent().SpanID(), "kubernetes requests should be children of the operation")
+
+	assert.Equal(suite.T(), "kubernetes.namespace.apply", apply.Name())
+	assert.Equal(suite.T(), codes.Error, apply.Status().Code)
+	assert.Len(suite.T(), apply.Events(), 1, "the error should be recorded")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	lbmeta "go.infratographer.com/load-balancer-api/pkg/metadata"
 	"go.infratographer.com/x/events"
 	"go.infratographer.com/x/gidx"
 )
@@ -20,6 +21,9 @@ type loadBalancer struct {
 	loadBalancerID gidx.PrefixedID
 	lbData         *lbapi.LoadBalancer
 	lbType         int
+	names          *lbNames
+	revision       int
+	state          lbmeta.LoadBalancerState
 }
 
 type Message interface {